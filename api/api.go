@@ -23,8 +23,10 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 )
@@ -52,7 +54,7 @@ type Record struct {
 	TTL        int         `json:"ttl"`
 	MinTTL     int         `json:"minttl"`
 	FQDN       string      `json:"fqdn"`
-	Priority   interface{} `json:"priority, string"` // Required only for SRV or MX records
+	Priority   interface{} `json:"priority"` // Required only for SRV or MX records, a number or a string
 	Subdomain  string      `json:"subdomain"`
 	Weight     int         `json:"weight"`     // Required only for SRV records
 	Port       int         `json:"port"`       // Required only for SRV records
@@ -63,6 +65,57 @@ type Record struct {
 	Expire     int         `json:"expire"`     // Required only for SOA records
 	NegCache   int         `json:"neg_cache"`  // Required only for SOA records
 	Operation  string      `json:"operation"`
+	// Fields are the fields set by an edit, the other fields keep their values.
+	// Without them an edit sets the non-empty fields.
+	Fields FieldSet `json:"-"`
+}
+
+// FieldSet is a set of the record fields changed by an edit
+type FieldSet uint
+
+const (
+	FieldSubdomain FieldSet = 1 << iota
+	FieldContent
+	FieldTTL
+	FieldPriority
+	FieldWeight
+	FieldPort
+	FieldTarget
+	FieldAdminMail
+	FieldRefresh
+	FieldRetry
+	FieldExpire
+	FieldNegCache
+)
+
+// fieldNames are the JSON names of the fields an edit can change
+var fieldNames = map[string]FieldSet{
+	"subdomain":  FieldSubdomain,
+	"content":    FieldContent,
+	"ttl":        FieldTTL,
+	"priority":   FieldPriority,
+	"weight":     FieldWeight,
+	"port":       FieldPort,
+	"target":     FieldTarget,
+	"admin_mail": FieldAdminMail,
+	"refresh":    FieldRefresh,
+	"retry":      FieldRetry,
+	"expire":     FieldExpire,
+	"neg_cache":  FieldNegCache,
+}
+
+// FieldByName returns the field by its JSON name, e.g. "admin_mail".
+func FieldByName(name string) (FieldSet, bool) {
+	f, ok := fieldNames[name]
+	return f, ok
+}
+
+// Sets reports whether the edit r changes the field, nonEmpty tells whether the field of r has a value.
+func (r *Record) Sets(f FieldSet, nonEmpty bool) bool {
+	if r.Fields == 0 {
+		return nonEmpty
+	}
+	return r.Fields&f != 0
 }
 
 type Response struct {
@@ -72,26 +125,46 @@ type Response struct {
 	Domain   string   `json:"domain"`
 	Success  string   `json:"success"`
 	Error    string   `json:"error"`
-	Json     string
+	Json     string   `json:"-"`
+	// HTTP status code of the response
+	StatusCode int `json:"-"`
 }
 
 type ApiError struct {
-	msg string
+	msg        string
+	HTTPStatus int
+}
+
+// DebugOutput receives the diagnostic messages, e.g. the request URLs
+var DebugOutput io.Writer = ioutil.Discard
+
+// Client sends the requests to the endpoint of the PDD API, the default endpoint is used if it is empty
+type Client struct {
+	Endpoint string
+	Token    string
 }
 
 func (e ApiError) Error() string {
 	return e.msg
 }
 
+// NewApiError creates the error as if it was returned by the API.
+func NewApiError(msg string, status int) ApiError {
+	return ApiError{msg, status}
+}
+
 func GetTokenLink() string {
 	return apiRequestGetToken
 }
 
-func doRequest(method string, command string, getParams string, token string) (Response, error) {
+func doRequest(endpoint, method string, command string, getParams string, token string) (Response, error) {
 	var response Response
 	client := &http.Client{}
-	urlStr := apiRequestPrefix + command + "?" + getParams
-	fmt.Printf("Request URL: %s\n\n", urlStr)
+	if endpoint == "" {
+		endpoint = apiRequestPrefix
+	}
+	urlStr := endpoint + command + "?" + getParams
+	fmt.Fprintf(DebugOutput, "Request URL: %s\n", urlStr)
 	res, err := http.NewRequest(method, urlStr, nil)
 	if err != nil {
 		return response, err
@@ -99,11 +172,10 @@ func doRequest(method string, command string, getParams string, token string) (R
 
 	res.Header.Set("PddToken", token)
 	resp, err := client.Do(res)
-	defer resp.Body.Close()
-
 	if err != nil {
 		return response, err
 	}
+	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -111,6 +183,10 @@ func doRequest(method string, command string, getParams string, token string) (R
 	}
 	err = json.Unmarshal(body, &response)
 	response.Json = string(body)
+	response.StatusCode = resp.StatusCode
+	if err != nil && resp.StatusCode >= 400 {
+		return response, ApiError{fmt.Sprintf("HTTP %d", resp.StatusCode), resp.StatusCode}
+	}
 	return response, err
 }
 
@@ -120,47 +196,58 @@ func recordToQueryString(r Record) string {
 		query = append(query, "record_id="+strconv.Itoa(r.RecordId))
 	}
 	if "" != r.RecordType {
-		query = append(query, "type="+r.RecordType)
+		query = append(query, "type="+url.QueryEscape(r.RecordType))
 	}
-	if "" != r.Content {
-		query = append(query, "content="+r.Content)
+	if r.Sets(FieldContent, "" != r.Content) {
+		query = append(query, "content="+url.QueryEscape(r.Content))
 	}
-	if 0 != r.TTL {
+	if r.Sets(FieldTTL, 0 != r.TTL) {
 		query = append(query, "ttl="+strconv.Itoa(r.TTL))
 	}
-	if "" != r.AdminMail {
-		query = append(query, "admin_mail="+r.AdminMail)
+	if r.Sets(FieldAdminMail, "" != r.AdminMail) {
+		query = append(query, "admin_mail="+url.QueryEscape(r.AdminMail))
 	}
-	if r.Priority != nil && "" != r.Priority.(string) {
-		query = append(query, "priority="+r.Priority.(string))
+	if priority := PriorityString(r.Priority); r.Sets(FieldPriority, "" != priority) {
+		query = append(query, "priority="+url.QueryEscape(priority))
 	}
-	if 0 != r.Weight {
+	if r.Sets(FieldWeight, 0 != r.Weight) {
 		query = append(query, "weight="+strconv.Itoa(r.Weight))
 	}
-	if 0 != r.Port {
+	if r.Sets(FieldPort, 0 != r.Port) {
 		query = append(query, "port="+strconv.Itoa(r.Port))
 	}
-	if "" != r.Target {
-		query = append(query, "target="+r.Target)
+	if r.Sets(FieldTarget, "" != r.Target) {
+		query = append(query, "target="+url.QueryEscape(r.Target))
 	}
-	if "" != r.Subdomain {
-		query = append(query, "subdomain="+r.Subdomain)
+	if r.Sets(FieldSubdomain, "" != r.Subdomain) {
+		query = append(query, "subdomain="+url.QueryEscape(r.Subdomain))
 	}
-	if 0 != r.Refresh {
+	if r.Sets(FieldRefresh, 0 != r.Refresh) {
 		query = append(query, "refresh="+strconv.Itoa(r.Refresh))
 	}
-	if 0 != r.Retry {
+	if r.Sets(FieldRetry, 0 != r.Retry) {
 		query = append(query, "retry="+strconv.Itoa(r.Retry))
 	}
-	if 0 != r.Expire {
+	if r.Sets(FieldExpire, 0 != r.Expire) {
 		query = append(query, "expire="+strconv.Itoa(r.Expire))
 	}
-	if 0 != r.NegCache {
+	if r.Sets(FieldNegCache, 0 != r.NegCache) {
 		query = append(query, "neg_cache="+strconv.Itoa(r.NegCache))
 	}
 	return strings.Join(query, "&")
 }
 
+// PriorityString formats the priority of a record which the API returns either as a number or as a string.
+func PriorityString(priority interface{}) string {
+	switch p := priority.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(p, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", priority)
+}
+
 func copyRecordParams(dst, src *Record) {
 	dst.RecordId = src.RecordId
 	dst.RecordType = src.RecordType
@@ -173,24 +260,32 @@ func copyRecordParams(dst, src *Record) {
 }
 
 func GetList(domain, token string) (Response, error) {
-	res, err := doRequest("GET", "list", "domain="+domain, token)
+	return Client{Token: token}.GetList(domain)
+}
+
+func (c Client) GetList(domain string) (Response, error) {
+	res, err := doRequest(c.Endpoint, "GET", "list", "domain="+url.QueryEscape(domain), c.Token)
 	if err != nil {
 		return res, err
 	}
 	if res.Success == ErrorAnswer {
-		return res, ApiError{res.Error}
+		return res, ApiError{res.Error, res.StatusCode}
 	}
 	return res, err
 }
 
 func AddRecord(r *Record, domain, token string) (Response, error) {
-	query := "domain=" + domain + "&" + recordToQueryString(*r)
-	res, err := doRequest("POST", "add", query, token)
+	return Client{Token: token}.AddRecord(r, domain)
+}
+
+func (c Client) AddRecord(r *Record, domain string) (Response, error) {
+	query := "domain=" + url.QueryEscape(domain) + "&" + recordToQueryString(*r)
+	res, err := doRequest(c.Endpoint, "POST", "add", query, c.Token)
 	if err != nil {
 		return res, err
 	}
 	if res.Success == ErrorAnswer {
-		return res, ApiError{res.Error}
+		return res, ApiError{res.Error, res.StatusCode}
 	}
 	copyRecordParams(r, &res.Record)
 
@@ -198,13 +293,17 @@ func AddRecord(r *Record, domain, token string) (Response, error) {
 }
 
 func EditRecord(r *Record, domain, token string) (Response, error) {
-	query := "domain=" + domain + "&" + recordToQueryString(*r)
-	res, err := doRequest("POST", "edit", query, token)
+	return Client{Token: token}.EditRecord(r, domain)
+}
+
+func (c Client) EditRecord(r *Record, domain string) (Response, error) {
+	query := "domain=" + url.QueryEscape(domain) + "&" + recordToQueryString(*r)
+	res, err := doRequest(c.Endpoint, "POST", "edit", query, c.Token)
 	if err != nil {
 		return res, err
 	}
 	if res.Success == ErrorAnswer {
-		return res, ApiError{res.Error}
+		return res, ApiError{res.Error, res.StatusCode}
 	}
 	copyRecordParams(r, &res.Record)
 
@@ -215,11 +314,18 @@ func DeleteRecord(r *Record, domain, token string) (Response, error) {
 	return DeleteRecordById(r.RecordId, domain, token)
 }
 func DeleteRecordById(id int, domain, token string) (Response, error) {
-	query := "domain=" + domain + "&record_id=" + strconv.Itoa(id)
-	res, err := doRequest("POST", "del", query, token)
+	return Client{Token: token}.DeleteRecordById(id, domain)
+}
+
+func (c Client) DeleteRecordById(id int, domain string) (Response, error) {
+	query := "domain=" + url.QueryEscape(domain) + "&record_id=" + strconv.Itoa(id)
+	res, err := doRequest(c.Endpoint, "POST", "del", query, c.Token)
+	if err != nil {
+		return res, err
+	}
 	if res.Success == ErrorAnswer {
-		return res, ApiError{res.Error}
+		return res, ApiError{res.Error, res.StatusCode}
 	}
 
-	return res, err
+	return res, nil
 }
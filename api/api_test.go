@@ -0,0 +1,40 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package api
+
+import "testing"
+
+func TestRecordToQueryString(t *testing.T) {
+	tests := []struct {
+		name string
+		r    Record
+		want string
+	}{
+		{"non-empty fields", Record{RecordId: 7, Content: "192.0.2.1", TTL: 300}, "record_id=7&content=192.0.2.1&ttl=300"},
+		{"set fields", Record{RecordId: 7, Content: "192.0.2.1", Weight: 0, Fields: FieldWeight | FieldTTL}, "record_id=7&ttl=0&weight=0"},
+		{"cleared priority", Record{RecordId: 7, Fields: FieldPriority}, "record_id=7&priority="},
+	}
+	for _, tt := range tests {
+		if got := recordToQueryString(tt.r); got != tt.want {
+			t.Errorf("%s: recordToQueryString() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
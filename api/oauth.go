@@ -0,0 +1,106 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+const (
+	oauthDeviceCodeURL string = "https://oauth.yandex.ru/device/code"
+	oauthTokenURL      string = "https://oauth.yandex.ru/token"
+)
+
+// ErrAuthorizationPending is returned by PollOAuthToken until the user confirms the code
+var ErrAuthorizationPending = errors.New("authorization_pending")
+
+// DeviceCode is the answer of the Yandex OAuth device code request
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURL string `json:"verification_url"`
+	Interval        int    `json:"interval"`
+	ExpiresIn       int    `json:"expires_in"`
+}
+
+// OAuthToken is the token issued by Yandex OAuth
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+}
+
+type oauthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func postOAuthForm(urlStr string, form url.Values, v interface{}) error {
+	resp, err := http.PostForm(urlStr, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var e oauthError
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			if e.Error == ErrAuthorizationPending.Error() {
+				return ErrAuthorizationPending
+			}
+			msg := e.Error
+			if e.Description != "" {
+				msg += ": " + e.Description
+			}
+			return ApiError{msg, resp.StatusCode}
+		}
+		return ApiError{resp.Status, resp.StatusCode}
+	}
+	return json.Unmarshal(data, v)
+}
+
+// RequestDeviceCode starts the OAuth device flow of the application.
+func RequestDeviceCode(clientId string) (DeviceCode, error) {
+	var code DeviceCode
+	err := postOAuthForm(oauthDeviceCodeURL, url.Values{"client_id": {clientId}}, &code)
+	return code, err
+}
+
+// PollOAuthToken exchanges the device code for the token once the user has confirmed it.
+func PollOAuthToken(clientId, clientSecret, deviceCode string) (OAuthToken, error) {
+	var token OAuthToken
+	form := url.Values{
+		"grant_type":    {"device_code"},
+		"code":          {deviceCode},
+		"client_id":     {clientId},
+		"client_secret": {clientSecret},
+	}
+	err := postOAuthForm(oauthTokenURL, form, &token)
+	return token, err
+}
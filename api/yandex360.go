@@ -0,0 +1,300 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	apiRequestPrefix360 string = "https://api360.yandex.net/directory/v1/org/"
+	perPage360          int    = 100
+)
+
+// Client360 sends the requests of the organization to the endpoint of the Yandex 360 Directory API,
+// the default endpoint is used if it is empty
+type Client360 struct {
+	Endpoint string
+	OrgId    string
+	Token    string
+}
+
+// record360 is the record model of the Yandex 360 DNS API
+type record360 struct {
+	RecordId   int    `json:"recordId"`
+	Type       string `json:"type"`
+	Name       string `json:"name"`
+	TTL        int    `json:"ttl"`
+	Address    string `json:"address"`
+	Exchange   string `json:"exchange"`
+	Preference int    `json:"preference"`
+	Text       string `json:"text"`
+	Target     string `json:"target"`
+	Priority   int    `json:"priority"`
+	Weight     int    `json:"weight"`
+	Port       int    `json:"port"`
+}
+
+type list360 struct {
+	Records []record360 `json:"records"`
+	Page    int         `json:"page"`
+	Pages   int         `json:"pages"`
+}
+
+type error360 struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (r record360) toRecord(domain string) Record {
+	rec := Record{
+		RecordId:   r.RecordId,
+		RecordType: r.Type,
+		Domain:     domain,
+		Subdomain:  r.Name,
+		TTL:        r.TTL,
+		FQDN:       domain,
+	}
+	if r.Name != "" && r.Name != "@" {
+		rec.FQDN = r.Name + "." + domain
+	}
+	switch r.Type {
+	case Type_A, Type_AAAA:
+		rec.Content = r.Address
+	case Type_MX:
+		rec.Content = r.Exchange
+		rec.Priority = strconv.Itoa(r.Preference)
+	case Type_TXT:
+		rec.Content = r.Text
+	case Type_SRV:
+		rec.Content = r.Target
+		rec.Target = r.Target
+		rec.Priority = strconv.Itoa(r.Priority)
+		rec.Weight = r.Weight
+		rec.Port = r.Port
+	default:
+		rec.Content = r.Target
+	}
+	return rec
+}
+
+// recordTo360 builds the request body, only the fields of the record type are sent.
+func recordTo360(r Record) map[string]interface{} {
+	body := map[string]interface{}{"type": strings.ToUpper(r.RecordType), "name": r.Subdomain}
+	if r.Subdomain == "" {
+		body["name"] = "@"
+	}
+	if r.TTL != 0 {
+		body["ttl"] = r.TTL
+	}
+	priority, _ := strconv.Atoi(PriorityString(r.Priority))
+	switch strings.ToUpper(r.RecordType) {
+	case Type_A, Type_AAAA:
+		body["address"] = r.Content
+	case Type_MX:
+		body["exchange"] = r.Content
+		body["preference"] = priority
+	case Type_TXT:
+		body["text"] = r.Content
+	case Type_SRV:
+		target := r.Target
+		if target == "" {
+			target = r.Content
+		}
+		body["target"] = target
+		body["priority"] = priority
+		body["weight"] = r.Weight
+		body["port"] = r.Port
+	default:
+		body["target"] = r.Content
+	}
+	return body
+}
+
+func (c Client360) doRequest(method, path string, query url.Values, body interface{}) ([]byte, int, error) {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = apiRequestPrefix360
+	}
+	urlStr := endpoint + url.QueryEscape(c.OrgId) + "/domains/" + path
+	if len(query) > 0 {
+		urlStr += "?" + query.Encode()
+	}
+	fmt.Fprintf(DebugOutput, "Request URL: %s %s\n", method, urlStr)
+
+	var reqBody *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, 0, err
+		}
+		reqBody = bytes.NewReader(data)
+	} else {
+		reqBody = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, urlStr, reqBody)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Authorization", "OAuth "+c.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	if resp.StatusCode >= 300 {
+		var e error360
+		if json.Unmarshal(data, &e) == nil && e.Message != "" {
+			return data, resp.StatusCode, ApiError{e.Message, resp.StatusCode}
+		}
+		return data, resp.StatusCode, ApiError{fmt.Sprintf("HTTP %d", resp.StatusCode), resp.StatusCode}
+	}
+	return data, resp.StatusCode, nil
+}
+
+// response360 wraps the records into the Response with the JSON in the PDD form.
+func response360(domain string, records []Record, status int) (Response, error) {
+	res := Response{Records: records, Domain: domain, Success: "ok", StatusCode: status}
+	if len(records) == 1 {
+		res.Record = records[0]
+		res.RecordId = records[0].RecordId
+	}
+	data, err := json.Marshal(res)
+	res.Json = string(data)
+	return res, err
+}
+
+// GetList returns the records of the domain of the Yandex 360 organization.
+func (c Client360) GetList(domain string) (Response, error) {
+	var records []Record
+	status := 0
+	for page := 1; ; page++ {
+		query := url.Values{"page": {strconv.Itoa(page)}, "perPage": {strconv.Itoa(perPage360)}}
+		data, code, err := c.doRequest("GET", url.QueryEscape(domain)+"/dns", query, nil)
+		status = code
+		if err != nil {
+			return Response{StatusCode: status, Json: string(data)}, err
+		}
+		var list list360
+		if err := json.Unmarshal(data, &list); err != nil {
+			return Response{StatusCode: status, Json: string(data)}, err
+		}
+		for _, r := range list.Records {
+			records = append(records, r.toRecord(domain))
+		}
+		if page >= list.Pages {
+			break
+		}
+	}
+	return response360(domain, records, status)
+}
+
+func (c Client360) saveRecord(method, path string, r *Record, domain string) (Response, error) {
+	data, status, err := c.doRequest(method, path, nil, recordTo360(*r))
+	if err != nil {
+		return Response{StatusCode: status, Json: string(data)}, err
+	}
+	var saved record360
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return Response{StatusCode: status, Json: string(data)}, err
+	}
+	rec := saved.toRecord(domain)
+	copyRecordParams(r, &rec)
+	return response360(domain, []Record{rec}, status)
+}
+
+// AddRecord creates the record in the domain of the Yandex 360 organization.
+func (c Client360) AddRecord(r *Record, domain string) (Response, error) {
+	return c.saveRecord("POST", url.QueryEscape(domain)+"/dns", r, domain)
+}
+
+// EditRecord changes the record with the ID of r. The API replaces the whole record,
+// so the fields set in r are merged into the current record like the PDD API does.
+func (c Client360) EditRecord(r *Record, domain string) (Response, error) {
+	list, err := c.GetList(domain)
+	if err != nil {
+		return list, err
+	}
+	for _, current := range list.Records {
+		if current.RecordId == r.RecordId {
+			merged := mergeRecord(current, *r)
+			return c.saveRecord("POST", url.QueryEscape(domain)+"/dns/"+strconv.Itoa(r.RecordId), &merged, domain)
+		}
+	}
+	return Response{StatusCode: http.StatusNotFound}, ApiError{"bad_record_id", http.StatusNotFound}
+}
+
+// mergeRecord sets the fields of the edit r on the current record, see Record.Sets.
+// A cleared TTL is not sent, so the API applies its default.
+func mergeRecord(current, r Record) Record {
+	if r.Sets(FieldSubdomain, r.Subdomain != "") {
+		current.Subdomain = r.Subdomain
+	}
+	if r.Sets(FieldContent, r.Content != "") {
+		current.Content = r.Content
+		if strings.ToUpper(current.RecordType) == Type_SRV && !r.Sets(FieldTarget, r.Target != "") {
+			current.Target = r.Content
+		}
+	}
+	if r.Sets(FieldTTL, r.TTL != 0) {
+		current.TTL = r.TTL
+	}
+	if r.Sets(FieldPriority, PriorityString(r.Priority) != "") {
+		current.Priority = r.Priority
+	}
+	if r.Sets(FieldWeight, r.Weight != 0) {
+		current.Weight = r.Weight
+	}
+	if r.Sets(FieldPort, r.Port != 0) {
+		current.Port = r.Port
+	}
+	if r.Sets(FieldTarget, r.Target != "") {
+		current.Target = r.Target
+	}
+	return current
+}
+
+// DeleteRecordById deletes the record by ID.
+func (c Client360) DeleteRecordById(id int, domain string) (Response, error) {
+	data, status, err := c.doRequest("DELETE", url.QueryEscape(domain)+"/dns/"+strconv.Itoa(id), nil, nil)
+	if err != nil {
+		return Response{StatusCode: status, Json: string(data)}, err
+	}
+	res := Response{RecordId: id, Domain: domain, Success: "ok", StatusCode: status}
+	encoded, err := json.Marshal(res)
+	res.Json = string(encoded)
+	return res, err
+}
@@ -0,0 +1,137 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fake360 serves the records of example.com of the organization 42 and remembers the last saved body.
+type fake360 struct {
+	records []record360
+	saved   map[string]interface{}
+}
+
+func (f *fake360) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const prefix = "/org/42/domains/example.com/dns"
+	if r.Header.Get("Authorization") != "OAuth token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == "GET" && r.URL.Path == prefix:
+		json.NewEncoder(w).Encode(list360{Records: f.records, Page: 1, Pages: 1})
+	case r.Method == "POST" && strings.HasPrefix(r.URL.Path, prefix+"/"):
+		data, _ := ioutil.ReadAll(r.Body)
+		f.saved = nil
+		json.Unmarshal(data, &f.saved)
+		w.Write(data)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code":5,"message":"not found"}`))
+	}
+}
+
+func startFake360(t *testing.T, records ...record360) (*fake360, Client360) {
+	f := &fake360{records: records}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, Client360{Endpoint: srv.URL + "/org/", OrgId: "42", Token: "token"}
+}
+
+func TestClient360EditRecordMergesCurrentRecord(t *testing.T) {
+	tests := []struct {
+		name    string
+		current record360
+		edit    Record
+		want    map[string]interface{}
+	}{
+		{
+			name:    "MX content",
+			current: record360{RecordId: 7, Type: "MX", Name: "mail", TTL: 3600, Exchange: "mx1.example.com.", Preference: 10},
+			edit:    Record{RecordId: 7, Content: "mx2.example.com."},
+			want:    map[string]interface{}{"type": "MX", "name": "mail", "ttl": 3600.0, "exchange": "mx2.example.com.", "preference": 10.0},
+		},
+		{
+			name:    "apex A ttl",
+			current: record360{RecordId: 8, Type: "A", Name: "@", TTL: 3600, Address: "192.0.2.1"},
+			edit:    Record{RecordId: 8, TTL: 300},
+			want:    map[string]interface{}{"type": "A", "name": "@", "ttl": 300.0, "address": "192.0.2.1"},
+		},
+		{
+			name:    "SRV port",
+			current: record360{RecordId: 9, Type: "SRV", Name: "_sip._tcp", TTL: 600, Target: "sip.example.com.", Priority: 10, Weight: 5, Port: 5060},
+			edit:    Record{RecordId: 9, Port: 5061},
+			want:    map[string]interface{}{"type": "SRV", "name": "_sip._tcp", "ttl": 600.0, "target": "sip.example.com.", "priority": 10.0, "weight": 5.0, "port": 5061.0},
+		},
+		{
+			name:    "SRV weight cleared",
+			current: record360{RecordId: 9, Type: "SRV", Name: "_sip._tcp", TTL: 600, Target: "sip.example.com.", Priority: 10, Weight: 5, Port: 5060},
+			edit:    Record{RecordId: 9, Weight: 0, Fields: FieldWeight},
+			want:    map[string]interface{}{"type": "SRV", "name": "_sip._tcp", "ttl": 600.0, "target": "sip.example.com.", "priority": 10.0, "weight": 0.0, "port": 5060.0},
+		},
+		{
+			name:    "ttl reset to the default",
+			current: record360{RecordId: 8, Type: "A", Name: "www", TTL: 3600, Address: "192.0.2.1"},
+			edit:    Record{RecordId: 8, Content: "192.0.2.2", Fields: FieldContent | FieldTTL},
+			want:    map[string]interface{}{"type": "A", "name": "www", "address": "192.0.2.2"},
+		},
+		{
+			name:    "only the set fields",
+			current: record360{RecordId: 10, Type: "TXT", Name: "www", TTL: 3600, Text: "v=spf1 -all"},
+			edit:    Record{RecordId: 10, Subdomain: "", Content: "v=spf1 ~all", TTL: 300, Fields: FieldSubdomain},
+			want:    map[string]interface{}{"type": "TXT", "name": "@", "ttl": 3600.0, "text": "v=spf1 -all"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, c := startFake360(t, tt.current)
+			edit := tt.edit
+			if _, err := c.EditRecord(&edit, "example.com"); err != nil {
+				t.Fatal(err)
+			}
+			if len(f.saved) != len(tt.want) {
+				t.Errorf("sent %v, want %v", f.saved, tt.want)
+			}
+			for key, want := range tt.want {
+				if f.saved[key] != want {
+					t.Errorf("sent %s = %v, want %v", key, f.saved[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestClient360EditRecordUnknownId(t *testing.T) {
+	f, c := startFake360(t, record360{RecordId: 7, Type: "A", Name: "www", Address: "192.0.2.1"})
+	_, err := c.EditRecord(&Record{RecordId: 8, Content: "192.0.2.2"}, "example.com")
+	if e, ok := err.(ApiError); !ok || e.HTTPStatus != http.StatusNotFound {
+		t.Errorf("error = %v, want a 404 ApiError", err)
+	}
+	if f.saved != nil {
+		t.Errorf("sent %v for an unknown record", f.saved)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	acmeChallengeLabel = "_acme-challenge"
+	acmeStateFileName  = ".yandexdns.acme.json"
+
+	certbotDomainEnvVar     = "CERTBOT_DOMAIN"
+	certbotValidationEnvVar = "CERTBOT_VALIDATION"
+)
+
+var acmeWait bool
+var acmeTTL int
+var acmeStateFile string
+
+// acmeCmd represents the acme command
+var acmeCmd = &cobra.Command{
+	Use:   "acme",
+	Short: "ACME DNS-01 challenge hooks for certbot, lego and acme.sh",
+	Long: `Creates and removes the _acme-challenge TXT records of the DNS-01 challenge.
+
+  certbot:  --manual-auth-hook "yandex-dns-cli-manager acme present --wait"
+            --manual-cleanup-hook "yandex-dns-cli-manager acme cleanup"
+            (the domain and the value are taken from $CERTBOT_DOMAIN and $CERTBOT_VALIDATION)
+  lego:     EXEC_PATH=/path/to/hook.sh where the script runs "yandex-dns-cli-manager acme $1 $2 $3"
+  acme.sh:  dns_yandexcli_add() / dns_yandexcli_rm() calling "acme present|cleanup $1 $2"
+
+The zone is the --domain setting or the longest matching domain of the profile.
+The IDs of the created records are kept in the state file and cleanup deletes exactly those records.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var acmePresentCmd = &cobra.Command{
+	Use:     "present [<fqdn> <value>]",
+	Aliases: []string{"add", "auth"},
+	Short:   "Create the challenge TXT record",
+	Run: func(cmd *cobra.Command, args []string) {
+		fqdn, value := acmeChallenge(args)
+		domain, subdomain := acmeZone(fqdn)
+		if acmeWait {
+			checkWaitFlags()
+		}
+		checkRequiredSettings()
+		defer lockDomain(domain)()
+
+		state, err := readAcmeState(acmeStatePath())
+		if err != nil {
+			throwError(err)
+		}
+		key := acmeStateKey(domain, subdomain, value)
+		if _, ok := state[key]; !ok {
+			r := api.Record{RecordType: typeTXT, Subdomain: subdomain, Content: value, TTL: acmeTTL}
+			if _, err := dnsProvider.Create(domain, &r); err != nil {
+				throwError(err)
+			}
+			state[key] = r.RecordId
+			if err := writeAcmeState(acmeStatePath(), state); err != nil {
+				throwError(err)
+			}
+			fmt.Printf("Created TXT record #%d %s.%s\n", r.RecordId, subdomain, domain)
+		}
+
+		if acmeWait && !checkPropagation(domain, subdomain, typeTXT, true) {
+			throwError(cliError{exitMismatch, errorCodeMismatch, "the nameservers do not serve the challenge record yet", "increase --timeout"})
+		}
+	},
+}
+
+var acmeCleanupCmd = &cobra.Command{
+	Use:     "cleanup [<fqdn> <value>]",
+	Aliases: []string{"rm"},
+	Short:   "Delete the challenge TXT record created by present",
+	Run: func(cmd *cobra.Command, args []string) {
+		fqdn, value := acmeChallenge(args)
+		domain, subdomain := acmeZone(fqdn)
+		checkRequiredSettings()
+		defer lockDomain(domain)()
+
+		state, err := readAcmeState(acmeStatePath())
+		if err != nil {
+			throwError(err)
+		}
+		key := acmeStateKey(domain, subdomain, value)
+		id, ok := state[key]
+		if !ok {
+			// created by another host or the state was lost: only a record with exactly this value is removed
+			list, err := dnsProvider.List(domain)
+			if err != nil {
+				throwError(err)
+			}
+			for _, r := range list.Records {
+				if strings.EqualFold(r.Subdomain, subdomain) && strings.ToUpper(r.RecordType) == typeTXT && r.Content == value {
+					id, ok = r.RecordId, true
+					break
+				}
+			}
+		}
+		if !ok {
+			fmt.Fprintf(os.Stderr, "No challenge record %s.%s with the value found\n", subdomain, domain)
+			return
+		}
+
+		if _, err := dnsProvider.Delete(domain, id); err != nil {
+			throwError(err)
+		}
+		delete(state, key)
+		if err := writeAcmeState(acmeStatePath(), state); err != nil {
+			throwError(err)
+		}
+		fmt.Printf("Deleted TXT record #%d %s.%s\n", id, subdomain, domain)
+	},
+}
+
+// acmeChallenge takes the name and the value of the challenge from the arguments (lego, acme.sh)
+// or from the environment variables of certbot.
+func acmeChallenge(args []string) (fqdn, value string) {
+	switch len(args) {
+	case 2:
+		return args[0], args[1]
+	case 0:
+		domain, value := os.Getenv(certbotDomainEnvVar), os.Getenv(certbotValidationEnvVar)
+		if domain == "" || value == "" {
+			throwError(usageError("The record name and value are required (or $%s and $%s).", certbotDomainEnvVar, certbotValidationEnvVar))
+		}
+		return acmeChallengeLabel + "." + domain, value
+	default:
+		throwError(usageError("The record name and value are required."))
+	}
+	return "", ""
+}
+
+// acmeZone splits the challenge name into the managed domain and the subdomain.
+func acmeZone(fqdn string) (domain, subdomain string) {
+	fqdn = strings.ToLower(strings.TrimSuffix(fqdn, "."))
+	if !strings.HasPrefix(fqdn, acmeChallengeLabel+".") {
+		fqdn = acmeChallengeLabel + "." + fqdn
+	}
+
+	candidates := toStringSlice(viper.Get(cfgKeyDomains))
+	if d := viper.GetString("domain"); d != "" {
+		candidates = append([]string{d}, candidates...)
+	}
+	for _, d := range candidates {
+		d = strings.ToLower(d)
+		if strings.HasSuffix(fqdn, "."+d) && len(d) > len(domain) {
+			domain = d
+		}
+	}
+	if domain == "" {
+		throwError(cliError{exitUsage, errorCodeUsage, fmt.Sprintf(`"%s" is not in a managed domain`, fqdn), "set --domain or the domains of the profile"})
+	}
+	viper.Set("domain", domain)
+	return domain, strings.TrimSuffix(fqdn, "."+domain)
+}
+
+func acmeStateKey(domain, subdomain, value string) string {
+	return strings.Join([]string{domain, subdomain, value}, "/")
+}
+
+func acmeStatePath() string {
+	if acmeStateFile != "" {
+		return acmeStateFile
+	}
+	return filepath.Join(filepath.Dir(getDefaultCfgFilepath()), acmeStateFileName)
+}
+
+func readAcmeState(filename string) (map[string]int, error) {
+	state := make(map[string]int)
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf(`Cannot parse state file "%s": %s`, filename, err)
+	}
+	return state, nil
+}
+
+func writeAcmeState(filename string, state map[string]int) error {
+	data, err := json.MarshalIndent(state, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0600)
+}
+
+func init() {
+	RootCmd.AddCommand(acmeCmd)
+	acmeCmd.AddCommand(acmePresentCmd, acmeCleanupCmd)
+
+	acmeCmd.PersistentFlags().StringVar(&acmeStateFile, "state", "", "file keeping the IDs of the created records (default is $HOME/"+acmeStateFileName+")")
+	acmePresentCmd.Flags().BoolVarP(&acmeWait, "wait", "w", false, "wait until the authoritative nameservers serve the record")
+	acmePresentCmd.Flags().IntVarP(&acmeTTL, "ttl", "l", 0, "the lifetime of the record in seconds")
+	addPropagationFlags(acmePresentCmd)
+}
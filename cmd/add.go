@@ -25,8 +25,6 @@ import (
 
 	"strings"
 
-	"errors"
-
 	"github.com/lexty/yandex-dns-cli-manager/api"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -36,14 +34,28 @@ import (
 var addCmd = &cobra.Command{
 	Use:   "add",
 	Short: "Add a new DNS record",
+	Long:  recordFlagsHelp,
 	Run: func(cmd *cobra.Command, args []string) {
-		resp, err := api.AddRecord(&rec, viper.GetString("domain"), viper.GetString("admin-token"))
+		if rec.RecordType == "" {
+			throwError(usageError("--type is required."))
+		}
+		if !containsString(recordTypes, strings.ToUpper(rec.RecordType)) {
+			throwError(usageError(`Unknown record type "%s".`, rec.RecordType))
+		}
+		checkRecordFlags(cmd, rec.RecordType)
+		checkRequiredRecordFlags(cmd, rec.RecordType)
+		checkRequiredSettings()
+		if !dnsProvider.Capabilities().SupportsType(rec.RecordType) {
+			throwError(usageError(`Record type "%s" is not supported by the provider "%s".`, rec.RecordType, providerName()))
+		}
+		defer lockDomain(viper.GetString("domain"))()
+		resp, err := dnsProvider.Create(viper.GetString("domain"), &rec)
 
 		if err != nil {
 			throwError(err)
 		}
 
-		switch viper.GetString("format") {
+		switch outputFormat() {
 		case formatJson:
 			fmt.Print(resp.Json)
 		case formatList:
@@ -51,7 +63,7 @@ var addCmd = &cobra.Command{
 			fmt.Print("Record successfully created\n\n")
 			printList(filterRecords([]api.Record{resp.Record}, []string{"*"}), strings.Join([]string{propId, propType, propContent, propSubdomain, propPriority, propTTL, propFQDN}, ","))
 		default:
-			throwError(errors.New(fmt.Sprintf(`Unknown output format "%s".`, viper.GetString("format"))))
+			throwError(usageError(`Unknown output format "%s".`, viper.GetString("format")))
 		}
 	},
 }
@@ -63,13 +75,6 @@ func init() {
 	viper.BindPFlag("format", addCmd.Flags().Lookup("format"))
 	viper.SetDefault("format", formatList)
 
-	addCmd.Flags().StringVarP(&rec.RecordType, "type", "t", "", fmt.Sprintf("type of record (available: %s)", strings.Join([]string{typeA, typeAAAA, typeCNAME, typeSRV, typeTXT, typeSOA, typeMX, typeNS}, ", ")))
-	addCmd.Flags().StringVarP(&rec.AdminMail, "admin-mail", "m", "", "email-address of the domain's administrator")
-	addCmd.Flags().StringVarP(&rec.Content, "content", "c", "", "content of the DNS record")
-	//addCmd.Flags().StringVarP(rec.Priority.(*string), "priority", "p", "", "priority of the DNS record")
-	addCmd.Flags().IntVarP(&rec.Weight, "weight", "w", 0, "weight of the SRV-record relative to other SRV-records for the same domain with the same priority")
-	addCmd.Flags().IntVarP(&rec.Port, "port", "P", 0, "TCP or UDP port of the host that is hosting the service")
-	addCmd.Flags().StringVarP(&rec.Target, "target", "T", "", "the canonical name of the host providing the service")
-	addCmd.Flags().StringVarP(&rec.Subdomain, "subdomain", "s", "", "Name of the subdomain")
-	addCmd.Flags().IntVarP(&rec.TTL, "ttl", "l", 0, "the lifetime of the DNS record in seconds")
+	addCmd.Flags().StringVarP(&rec.RecordType, "type", "t", "", recordTypesHelp())
+	addRecordFlags(addCmd)
 }
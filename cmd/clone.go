@@ -0,0 +1,184 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/lexty/yandex-dns-cli-manager/provider"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var cloneSourceProfile string
+var cloneTypes string
+var cloneMatch string
+var cloneWithNS bool
+var cloneYes bool
+var cloneDryRun bool
+
+// cloneCmd represents the clone command
+var cloneCmd = &cobra.Command{
+	Use:   "clone <source-domain>",
+	Short: "Copy the records of another domain",
+	Long: `Copies the records of the source domain to the domain (--domain). The content referring to the source
+domain is rewritten to the domain, e.g. "mail.example.com" becomes "mail.example.org". SOA and NS records
+are skipped (NS are copied with --with-ns). The records already present are skipped and the ones
+conflicting with the present records (e.g. a CNAME at a name with other records) are reported.
+The source domain can be read with the settings of another profile (--source-profile).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			throwError(usageError("The source domain is required."))
+		}
+		source := strings.ToLower(args[0])
+		checkRequiredSettings()
+		domain := viper.GetString("domain")
+		if strings.EqualFold(source, domain) {
+			throwError(usageError("The source domain is the domain itself."))
+		}
+		defer lockDomain(domain)()
+
+		sourceProvider := dnsProvider
+		if cloneSourceProfile != "" {
+			settings := profileSettings(cloneSourceProfile)
+			backend := settings(cfgKeyBackend)
+			if backend == "" {
+				backend = provider.NamePDD
+			}
+			var err error
+			if sourceProvider, err = provider.New(backend, settings); err != nil {
+				throwError(usageError(`Cannot use the profile "%s": %s`, cloneSourceProfile, err))
+			}
+		}
+		sourceList, err := sourceProvider.List(source)
+		if err != nil {
+			throwError(err)
+		}
+		list, err := dnsProvider.List(domain)
+		if err != nil {
+			throwError(err)
+		}
+
+		live := make(map[string]bool, len(list.Records))
+		names := make(map[string][]string)
+		for i := range list.Records {
+			r := &list.Records[i]
+			live[recordKey(r)] = true
+			names[lintName(r)] = append(names[lintName(r)], strings.ToUpper(r.RecordType))
+		}
+
+		var missing []api.Record
+		present, conflicts := 0, 0
+		for i := range sourceList.Records {
+			r := &sourceList.Records[i]
+			if !matchesCloneSelector(r) {
+				continue
+			}
+			c := copyRecord(r, lintName(r))
+			c.Content = rewriteDomain(c.Content, source, domain)
+			c.Target = rewriteDomain(c.Target, source, domain)
+			name := lintName(&c)
+			switch {
+			case live[recordKey(&c)]:
+				fmt.Printf("  = %s\n", formatRecord(&c))
+				present++
+			case len(names[name]) > 0 && (c.RecordType == typeCNAME || containsString(names[name], typeCNAME)):
+				fmt.Printf("  ! %s conflicts with the %s records of %s\n", formatRecord(&c), strings.Join(names[name], ", "), name)
+				conflicts++
+			default:
+				fmt.Printf("  + %s\n", formatRecord(&c))
+				missing = append(missing, c)
+				names[name] = append(names[name], c.RecordType)
+			}
+		}
+		fmt.Printf("%d to create, %d already present, %d conflicts\n", len(missing), present, conflicts)
+		if len(missing) == 0 || cloneDryRun || (!cloneYes && !confirm(fmt.Sprintf("Add %d record(s) to %s?", len(missing), domain))) {
+			return
+		}
+
+		for i := range missing {
+			if _, err := dnsProvider.Create(domain, &missing[i]); err != nil {
+				throwError(err)
+			}
+			fmt.Printf("Record successfully created: %s\n", formatRecord(&missing[i]))
+		}
+	},
+}
+
+// matchesCloneSelector reports whether the source record is selected by --type, --match and --with-ns.
+func matchesCloneSelector(r *api.Record) bool {
+	recordType := strings.ToUpper(r.RecordType)
+	if recordType == typeSOA || (recordType == typeNS && (!cloneWithNS || lintName(r) == apexLabel)) {
+		return false
+	}
+	if cloneTypes != "" && !containsString(splitList(strings.ToUpper(cloneTypes)), recordType) {
+		return false
+	}
+	matched, err := path.Match(cloneMatch, lintName(r))
+	if err != nil {
+		throwError(usageError(`Invalid pattern "%s": %s`, cloneMatch, err))
+	}
+	return matched
+}
+
+// rewriteDomain replaces the host names in the source domain with the ones in the target domain.
+// Only whole labels are replaced: "example.com" matches "mail.example.com" but not "myexample.com" or "example.com.au".
+func rewriteDomain(s, from, to string) string {
+	lower := strings.ToLower(s)
+	from = strings.ToLower(from)
+	var out []string
+	last := 0
+	for i := 0; i+len(from) <= len(lower); {
+		j := strings.Index(lower[i:], from)
+		if j < 0 {
+			break
+		}
+		start, end := i+j, i+j+len(from)
+		after := end
+		if after < len(lower) && lower[after] == '.' {
+			after++
+		}
+		if (start == 0 || !isLabelChar(lower[start-1])) && (after == len(lower) || !isLabelChar(lower[after]) && lower[after] != '.') {
+			out = append(out, s[last:start], to)
+			last = end
+		}
+		i = end
+	}
+	return strings.Join(out, "") + s[last:]
+}
+
+func isLabelChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+}
+
+func init() {
+	RootCmd.AddCommand(cloneCmd)
+
+	cloneCmd.Flags().StringVarP(&cloneSourceProfile, "source-profile", "P", "", "profile with the settings to read the source domain (default is the active settings)")
+	cloneCmd.Flags().StringVarP(&cloneTypes, "type", "t", "", "comma separated record types to copy (default is all but SOA and NS)")
+	cloneCmd.Flags().StringVarP(&cloneMatch, "match", "m", "*", `subdomain pattern of the records to copy, e.g. "*.api" ("@" is the domain itself)`)
+	cloneCmd.Flags().BoolVar(&cloneWithNS, "with-ns", false, "copy the NS records of the subdomains as well")
+	cloneCmd.Flags().BoolVarP(&cloneYes, "yes", "y", false, "do not ask for confirmation")
+	cloneCmd.Flags().BoolVarP(&cloneDryRun, "dry-run", "n", false, "only show the changes")
+}
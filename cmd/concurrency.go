@@ -0,0 +1,126 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/spf13/cobra"
+)
+
+const lockDirName = ".yandexdns.locks"
+
+var ifContent string
+var ifTTL int
+var ifMatch string
+
+// recordFingerprint identifies the state of the record, it is shown by "list --props fingerprint"
+// and checked by --if-match.
+func recordFingerprint(r *api.Record) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d\x00%s\x00%d", r.RecordId, recordKey(r), r.TTL)))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// lockDomain takes the advisory lock of the domain so the commands changing the records of one domain
+// on this machine run one after another. The lock is released by the returned function or when the program exits.
+// The function must be kept, e.g. "defer lockDomain(domain)()": the garbage collector closes an unreachable
+// lock file and releases the lock.
+func lockDomain(domain string) func() {
+	unlock, err := tryLockDomain(domain)
+	if err != nil {
+		throwError(err)
+	}
+	return unlock
+}
+
+// tryLockDomain is lockDomain returning the error, e.g. to answer a request of "serve".
+func tryLockDomain(domain string) (func(), error) {
+	dir := filepath.Join(filepath.Dir(getDefaultCfgFilepath()), lockDirName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, strings.ToLower(domain)+".lock"), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if lockFile(f, false) != nil {
+		fmt.Fprintf(os.Stderr, "Waiting for another command changing %s...\n", domain)
+		if err := lockFile(f, true); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// addConditionFlags registers the flags of the expected state of the changed record.
+func addConditionFlags(c *cobra.Command) {
+	c.Flags().StringVar(&ifContent, "if-content", "", "change the record only if its content is the value")
+	c.Flags().IntVar(&ifTTL, "if-ttl", 0, "change the record only if its TTL is the value")
+	c.Flags().StringVar(&ifMatch, "if-match", "", `change the record only if its fingerprint (shown by "list --props id,fingerprint") is the value`)
+}
+
+// checkConditions fails if the record does not match the --if-* flags passed to the command.
+func checkConditions(c *cobra.Command, r *api.Record) {
+	var mismatch string
+	switch {
+	case c.Flags().Lookup("if-content").Changed && strings.TrimSuffix(r.Content, ".") != strings.TrimSuffix(ifContent, "."):
+		mismatch = fmt.Sprintf(`its content is "%s"`, r.Content)
+	case c.Flags().Lookup("if-ttl").Changed && r.TTL != ifTTL:
+		mismatch = fmt.Sprintf("its TTL is %d", r.TTL)
+	case ifMatch != "" && !strings.EqualFold(recordFingerprint(r), ifMatch):
+		mismatch = fmt.Sprintf("its fingerprint is %s", recordFingerprint(r))
+	default:
+		return
+	}
+	throwError(cliError{exitMismatch, errorCodeMismatch, fmt.Sprintf("record #%d was changed, %s", r.RecordId, mismatch), "list the records again and retry"})
+}
+
+// verifyUnchanged fails if any of the records was changed or deleted since it was listed,
+// the bulk commands call it after the confirmation before changing the records.
+func verifyUnchanged(domain string, records []*api.Record) {
+	list, err := dnsProvider.List(domain)
+	if err != nil {
+		throwError(err)
+	}
+	current := make(map[int]*api.Record, len(list.Records))
+	for i := range list.Records {
+		current[list.Records[i].RecordId] = &list.Records[i]
+	}
+	for _, r := range records {
+		c, ok := current[r.RecordId]
+		if !ok {
+			throwError(cliError{exitMismatch, errorCodeMismatch, fmt.Sprintf("record #%d was deleted meanwhile", r.RecordId), "run the command again"})
+		}
+		if recordFingerprint(c) != recordFingerprint(r) {
+			throwError(cliError{exitMismatch, errorCodeMismatch, fmt.Sprintf("record #%d was changed meanwhile: %s", r.RecordId, formatRecord(c)), "run the command again"})
+		}
+	}
+}
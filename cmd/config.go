@@ -0,0 +1,213 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	cfgKeyProfile  = "profile"
+	cfgKeyProfiles = "profiles"
+	cfgKeyDomains  = "domains"
+	cfgKeyEndpoint = "endpoint"
+	cfgKeyBackend  = "backend"
+	cfgKeyOrgId    = "org-id"
+
+	profileEnvVar = "YANDEX_DNS_PROFILE"
+)
+
+// configFilepath returns the path of the config file in use.
+func configFilepath() string {
+	if cfgFile != "" {
+		return cfgFile
+	}
+	if used := viper.ConfigFileUsed(); used != "" {
+		if _, err := os.Stat(used); err == nil {
+			return used
+		}
+	}
+	return getDefaultCfgFilepath()
+}
+
+// readConfigFile reads the raw content of the config file keeping all keys, even unknown ones.
+// A missing file is treated as an empty config.
+func readConfigFile() (map[string]interface{}, error) {
+	cfg := make(map[string]interface{})
+	data, err := ioutil.ReadFile(configFilepath())
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, usageError(`Cannot parse config file "%s": %s`, configFilepath(), err)
+	}
+	return cfg, nil
+}
+
+func writeConfigFile(cfg map[string]interface{}) error {
+	data, err := json.MarshalIndent(cfg, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(configFilepath(), append(data, '\n'), 0600)
+}
+
+// configProfiles returns the profiles section of the raw config, creating it if necessary.
+func configProfiles(cfg map[string]interface{}) map[string]interface{} {
+	profiles, ok := cfg[cfgKeyProfiles].(map[string]interface{})
+	if !ok {
+		profiles = make(map[string]interface{})
+		cfg[cfgKeyProfiles] = profiles
+	}
+	return profiles
+}
+
+// selectedConfigSection returns the profile selected with --profile or the top level settings.
+func selectedConfigSection(cfg map[string]interface{}) map[string]interface{} {
+	if profileName == "" {
+		return cfg
+	}
+	profile, ok := configProfiles(cfg)[profileName].(map[string]interface{})
+	if !ok {
+		throwError(usageError(`Unknown profile "%s".`, profileName))
+	}
+	return profile
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// activeProfileName returns the profile selected by --profile, the environment or the config file.
+func activeProfileName() string {
+	if profileName != "" {
+		return profileName
+	}
+	if name := os.Getenv(profileEnvVar); name != "" {
+		return name
+	}
+	return viper.GetString(cfgKeyProfile)
+}
+
+// applyProfile overrides the settings with the values of the active profile.
+// Flags and environment variables still take precedence.
+// The profile is read from the raw config file because viper lowercases the profile names.
+func applyProfile() {
+	name := activeProfileName()
+	if name == "" {
+		return
+	}
+	if c, _, err := RootCmd.Find(os.Args[1:]); err == nil && c == profileAddCmd {
+		// the selected profile may be the one being added
+		return
+	}
+	cfg, err := readConfigFile()
+	if err != nil {
+		throwError(err)
+	}
+	profile, ok := configProfiles(cfg)[name].(map[string]interface{})
+	if !ok {
+		throwError(usageError(`Unknown profile "%s".`, name))
+	}
+	source := fmt.Sprintf(`profile "%s"`, name)
+	for _, key := range tokenSourceKeys {
+		if _, ok := profile[key]; ok {
+			// the token of the profile overrides any token of the top level settings
+			for _, other := range tokenSourceKeys {
+				if !isOverridden(other) {
+					viper.Set(other, "")
+				}
+			}
+			break
+		}
+	}
+	for key, value := range profile {
+		if key == cfgKeyDomains {
+			domains := toStringSlice(value)
+			viper.Set(cfgKeyDomains, domains)
+			settingSources[cfgKeyDomains] = source
+			if len(domains) > 0 && !isOverridden("domain") {
+				viper.Set("domain", domains[0])
+				settingSources["domain"] = source
+			}
+			continue
+		}
+		if !isOverridden(key) {
+			viper.Set(key, value)
+			settingSources[key] = source
+		}
+	}
+}
+
+func toStringSlice(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return parseCommaSep(v)
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// isFlagChanged reports whether the flag was passed to the executed command.
+func isFlagChanged(name string) bool {
+	for _, c := range allCommands(RootCmd) {
+		if f := c.Flags().Lookup(name); f != nil && f.Changed {
+			return true
+		}
+		if f := c.PersistentFlags().Lookup(name); f != nil && f.Changed {
+			return true
+		}
+	}
+	return false
+}
+
+// allCommands returns the command and all its descendants.
+func allCommands(c *cobra.Command) []*cobra.Command {
+	list := []*cobra.Command{c}
+	for _, child := range c.Commands() {
+		list = append(list, allCommands(child)...)
+	}
+	return list
+}
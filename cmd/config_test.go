@@ -0,0 +1,62 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestProfileFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := `{"domain": "example.org", "profiles": {"p": {"domain": "example.com", "format": "table"}}}`
+	if err := ioutil.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer func(file, profile, format, domain string) {
+		cfgFile, profileName = file, profile
+		viper.Set("format", format)
+		viper.Set("domain", domain)
+	}(cfgFile, profileName, viper.GetString("format"), viper.GetString("domain"))
+	cfgFile, profileName = path, "p"
+
+	applyProfile()
+	if got := viper.GetString("domain"); got != "example.com" {
+		t.Errorf("domain = %q", got)
+	}
+	// list prints the table of the profile, add, edit and delete print their message
+	if got := viper.GetString("format"); got != formatTable {
+		t.Errorf("format = %q", got)
+	}
+	if got := outputFormat(); got != formatList {
+		t.Errorf("outputFormat() = %q, want %q", got, formatList)
+	}
+
+	for format, want := range map[string]string{formatJson: formatJson, formatTree: formatList, formatList: formatList, "xml": "xml"} {
+		viper.Set("format", format)
+		if got := outputFormat(); got != want {
+			t.Errorf("outputFormat() of %s = %q, want %q", format, got, want)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	ddnsSourceHTTP      = "http"
+	ddnsSourceInterface = "interface"
+	ddnsSourceCommand   = "command"
+
+	ddnsStateFileName = ".yandexdns.ddns.json"
+)
+
+var ddnsSource string
+var ddnsURL4 string
+var ddnsURL6 string
+var ddnsInterface string
+var ddnsCommand string
+var ddnsIPv4 bool
+var ddnsIPv6 bool
+var ddnsInterval time.Duration
+var ddnsJitter time.Duration
+var ddnsStateFile string
+var ddnsOnce bool
+
+// ddnsEntry is the last address written to a record
+type ddnsEntry struct {
+	Address  string    `json:"address"`
+	RecordId int       `json:"record_id"`
+	Updated  time.Time `json:"updated"`
+}
+
+// ddnsCmd represents the ddns command
+var ddnsCmd = &cobra.Command{
+	Use:   "ddns <subdomain>...",
+	Short: "Keep A/AAAA records pointed at the current public address",
+	Long: `Determines the current public IPv4 and/or IPv6 address and updates the A/AAAA records
+of the subdomains when the address changes. The address is taken from an HTTP echo endpoint (default),
+a network interface or the output of a command. Runs until interrupted unless --once is given;
+the last written addresses are kept in the state file so restarts do not touch the records.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			throwError(usageError("At least one subdomain is required."))
+		}
+		if !ddnsIPv4 && !ddnsIPv6 {
+			throwError(usageError("Nothing to update: both --ipv4 and --ipv6 are disabled."))
+		}
+		switch ddnsSource {
+		case ddnsSourceHTTP:
+		case ddnsSourceInterface:
+			if ddnsInterface == "" {
+				throwError(usageError("--interface is required for the interface source."))
+			}
+		case ddnsSourceCommand:
+			if ddnsCommand == "" {
+				throwError(usageError("--command is required for the command source."))
+			}
+		default:
+			throwError(usageError(`Unknown address source "%s".`, ddnsSource))
+		}
+		if ddnsInterval <= 0 {
+			throwError(usageError("--interval must be positive."))
+		}
+		if ddnsJitter < 0 {
+			throwError(usageError("--jitter must not be negative."))
+		}
+		checkRequiredSettings()
+		domain := viper.GetString("domain")
+
+		stateFile := ddnsStateFile
+		if stateFile == "" {
+			stateFile = filepath.Join(filepath.Dir(getDefaultCfgFilepath()), ddnsStateFileName)
+		}
+		state, err := readDdnsState(stateFile)
+		if err != nil {
+			throwError(err)
+		}
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		rand.Seed(time.Now().UnixNano())
+
+		for {
+			unlock := lockDomain(domain)
+			err := updateDdns(domain, args, state, stateFile)
+			unlock()
+			if ddnsOnce {
+				if err != nil {
+					throwError(err)
+				}
+				return
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s  Error: %s\n", time.Now().Format(time.RFC3339), err)
+			}
+
+			delay := ddnsInterval
+			if ddnsJitter > 0 {
+				delay += time.Duration(rand.Int63n(int64(ddnsJitter)))
+			}
+			select {
+			case s := <-signals:
+				fmt.Printf("%s  %s received, exiting\n", time.Now().Format(time.RFC3339), s)
+				return
+			case <-time.After(delay):
+			}
+		}
+	},
+}
+
+// updateDdns points the records at the current addresses, the records are listed only when an address changed.
+func updateDdns(domain string, subdomains []string, state map[string]ddnsEntry, stateFile string) error {
+	addrs, err := currentAddresses()
+	if err != nil {
+		return err
+	}
+
+	var records []api.Record
+	listed := false
+	for _, recordType := range []string{typeA, typeAAAA} {
+		addr, ok := addrs[recordType]
+		if !ok {
+			continue
+		}
+		for _, subdomain := range subdomains {
+			key := strings.Join([]string{domain, subdomain, recordType}, "/")
+			if state[key].Address == addr {
+				continue
+			}
+			if !listed {
+				list, err := dnsProvider.List(domain)
+				if err != nil {
+					return err
+				}
+				records, listed = list.Records, true
+			}
+
+			r := findDdnsRecord(records, subdomain, recordType)
+			if r == nil {
+				fmt.Fprintf(os.Stderr, "%s  Error: no %s record of %s found, add it first\n", time.Now().Format(time.RFC3339), recordType, subdomain)
+				continue
+			}
+			if r.Content != addr {
+				update := *r
+				update.Content = addr
+				if _, err := dnsProvider.Update(domain, &update); err != nil {
+					return err
+				}
+				fmt.Printf("%s  %s %s: %s -> %s\n", time.Now().Format(time.RFC3339), subdomain, recordType, r.Content, addr)
+			}
+			state[key] = ddnsEntry{addr, r.RecordId, time.Now()}
+			if err := writeDdnsState(stateFile, state); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func findDdnsRecord(records []api.Record, subdomain, recordType string) *api.Record {
+	for i := range records {
+		r := &records[i]
+		recSubdomain := r.Subdomain
+		if recSubdomain == "" {
+			recSubdomain = apexLabel
+		}
+		if strings.EqualFold(recSubdomain, subdomain) && strings.ToUpper(r.RecordType) == recordType {
+			return r
+		}
+	}
+	return nil
+}
+
+// currentAddresses returns the current addresses by record type (A, AAAA).
+func currentAddresses() (map[string]string, error) {
+	addrs := make(map[string]string)
+	var candidates []string
+	switch ddnsSource {
+	case ddnsSourceInterface:
+		iface, err := net.InterfaceByName(ddnsInterface)
+		if err != nil {
+			return nil, err
+		}
+		ifAddrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range ifAddrs {
+			// the private (RFC 1918) and unique local (RFC 4193) addresses are not reachable from the internet
+			if ipNet, ok := a.(*net.IPNet); ok && ipNet.IP.IsGlobalUnicast() && !ipNet.IP.IsPrivate() {
+				candidates = append(candidates, ipNet.IP.String())
+			}
+		}
+	case ddnsSourceCommand:
+		out, err := exec.Command("sh", "-c", ddnsCommand).Output()
+		if err != nil {
+			return nil, fmt.Errorf(`command "%s" failed: %s`, ddnsCommand, err)
+		}
+		candidates = strings.Fields(string(out))
+	default:
+		if ddnsIPv4 {
+			addr, err := echoAddress(ddnsURL4)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, addr)
+		}
+		if ddnsIPv6 {
+			addr, err := echoAddress(ddnsURL6)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, addr)
+		}
+	}
+
+	for _, c := range candidates {
+		ip := net.ParseIP(c)
+		if ip == nil {
+			continue
+		}
+		recordType := typeAAAA
+		if ip.To4() != nil {
+			recordType = typeA
+		}
+		if _, ok := addrs[recordType]; !ok {
+			addrs[recordType] = ip.String()
+		}
+	}
+	if ddnsIPv4 && addrs[typeA] == "" {
+		return nil, fmt.Errorf("no IPv4 address found with the %s source", ddnsSource)
+	}
+	if ddnsIPv6 && addrs[typeAAAA] == "" {
+		return nil, fmt.Errorf("no IPv6 address found with the %s source", ddnsSource)
+	}
+	if !ddnsIPv4 {
+		delete(addrs, typeA)
+	}
+	if !ddnsIPv6 {
+		delete(addrs, typeAAAA)
+	}
+	return addrs, nil
+}
+
+// echoAddress asks an HTTP endpoint that answers with the address of the client in plain text.
+func echoAddress(url string) (string, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s answered HTTP %d", url, resp.StatusCode)
+	}
+	addr := strings.TrimSpace(string(body))
+	if net.ParseIP(addr) == nil {
+		return "", fmt.Errorf(`%s answered "%s" which is not an IP address`, url, addr)
+	}
+	return addr, nil
+}
+
+func readDdnsState(filename string) (map[string]ddnsEntry, error) {
+	state := make(map[string]ddnsEntry)
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf(`Cannot parse state file "%s": %s`, filename, err)
+	}
+	return state, nil
+}
+
+// writeDdnsState replaces the state file atomically so an interrupted daemon never leaves it truncated.
+func writeDdnsState(filename string, state map[string]ddnsEntry) error {
+	data, err := json.MarshalIndent(state, "", "    ")
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+func init() {
+	RootCmd.AddCommand(ddnsCmd)
+
+	ddnsCmd.Flags().StringVarP(&ddnsSource, "source", "S", ddnsSourceHTTP, fmt.Sprintf("where to take the address from (%s|%s|%s)", ddnsSourceHTTP, ddnsSourceInterface, ddnsSourceCommand))
+	ddnsCmd.Flags().StringVar(&ddnsURL4, "url", "https://api.ipify.org", "HTTP endpoint answering with the public IPv4 address")
+	ddnsCmd.Flags().StringVar(&ddnsURL6, "url6", "https://api6.ipify.org", "HTTP endpoint answering with the public IPv6 address")
+	ddnsCmd.Flags().StringVarP(&ddnsInterface, "interface", "I", "", "network interface to take the address from")
+	ddnsCmd.Flags().StringVarP(&ddnsCommand, "command", "c", "", "shell command printing the address(es)")
+	ddnsCmd.Flags().BoolVarP(&ddnsIPv4, "ipv4", "4", true, "update the A records")
+	ddnsCmd.Flags().BoolVarP(&ddnsIPv6, "ipv6", "6", false, "update the AAAA records")
+	ddnsCmd.Flags().DurationVarP(&ddnsInterval, "interval", "i", 5*time.Minute, "interval between the checks")
+	ddnsCmd.Flags().DurationVarP(&ddnsJitter, "jitter", "j", 30*time.Second, "random delay added to the interval")
+	ddnsCmd.Flags().StringVar(&ddnsStateFile, "state", "", "file keeping the last written addresses (default is $HOME/"+ddnsStateFileName+")")
+	ddnsCmd.Flags().BoolVar(&ddnsOnce, "once", false, "update the records once and exit")
+}
@@ -0,0 +1,153 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	cfgKeyCommands = "commands"
+	cfgKeyAliases  = "aliases"
+)
+
+// keys bound to the flags of several commands, they are rebound to the flags of the executed command
+var commandViperKeys = []string{"format", "props", "types", "sort"}
+
+// applyCommandDefaults binds the flags of the executed command and applies its defaults from the
+// "commands" section of the config file, e.g. {"commands": {"list": {"format": "table"}}}.
+// Flags and environment variables still take precedence.
+func applyCommandDefaults(cmd *cobra.Command, args []string) {
+	for _, key := range commandViperKeys {
+		if f := cmd.Flags().Lookup(key); f != nil {
+			viper.BindPFlag(key, f)
+		}
+	}
+
+	name := strings.TrimPrefix(cmd.CommandPath(), RootCmd.Name()+" ")
+	defaults, ok := viper.GetStringMap(cfgKeyCommands)[name].(map[string]interface{})
+	if !ok {
+		return
+	}
+	source := fmt.Sprintf(`command defaults "%s"`, name)
+	for key, value := range defaults {
+		if isOverridden(key) {
+			continue
+		}
+		if f := cmd.Flags().Lookup(key); f != nil {
+			// the flag gets a new default, it is not marked as changed as if passed on the command line
+			if err := f.Value.Set(fmt.Sprint(value)); err != nil {
+				throwError(usageError(`Invalid default "%s" of the command "%s": %s`, key, name, err))
+			}
+			f.DefValue = f.Value.String()
+		}
+		viper.Set(key, value)
+		settingSources[key] = source
+	}
+}
+
+// expandAlias replaces an alias from the "aliases" section of the config file
+// with its command line, e.g. {"aliases": {"mx": "list --types MX --format table"}}.
+func expandAlias(args []string) []string {
+	pos := commandArgPos(args)
+	if pos < 0 {
+		return args
+	}
+	if c, _, err := RootCmd.Find(args[pos : pos+1]); err == nil && c != RootCmd {
+		return args
+	}
+
+	for i := 0; i < pos; i++ {
+		if args[i] == "--config" && i+1 < len(args) {
+			cfgFile = args[i+1]
+		} else if strings.HasPrefix(args[i], "--config=") {
+			cfgFile = strings.TrimPrefix(args[i], "--config=")
+		}
+	}
+	cfg, err := readConfigFile()
+	if err != nil {
+		return args
+	}
+	aliases, _ := cfg[cfgKeyAliases].(map[string]interface{})
+	alias, ok := aliases[args[pos]].(string)
+	if !ok {
+		return args
+	}
+
+	expanded := append([]string{}, args[:pos]...)
+	expanded = append(expanded, splitCommandLine(alias)...)
+	return append(expanded, args[pos+1:]...)
+}
+
+// commandArgPos returns the position of the first argument which is not a global flag or its value.
+func commandArgPos(args []string) int {
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--config", "--admin-token", "-a", "--domain", "-d", "--profile":
+			i++
+			continue
+		}
+		if !strings.HasPrefix(args[i], "-") {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitCommandLine splits the line into arguments by spaces, respecting single and double quotes.
+func splitCommandLine(line string) []string {
+	var args []string
+	var current []rune
+	var quote rune
+	inArg := false
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current = append(current, r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, string(current))
+				current = current[:0]
+				inArg = false
+			}
+		default:
+			current = append(current, r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, string(current))
+	}
+	return args
+}
+
+func init() {
+	RootCmd.PersistentPreRun = applyCommandDefaults
+}
@@ -23,9 +23,6 @@ package cmd
 import (
 	"fmt"
 
-	"errors"
-
-	"github.com/lexty/yandex-dns-cli-manager/api"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -37,19 +34,24 @@ var deleteCmd = &cobra.Command{
 	Use:   "delete",
 	Short: "Delete the DNS record by ID",
 	Run: func(cmd *cobra.Command, args []string) {
-		resp, err := api.DeleteRecordById(id, viper.GetString("domain"), viper.GetString("admin-token"))
+		checkRequiredSettings()
+		defer lockDomain(viper.GetString("domain"))()
+		if anyFlagChanged(cmd, "if-content", "if-ttl", "if-match") {
+			checkConditions(cmd, currentRecord(viper.GetString("domain"), id))
+		}
+		resp, err := dnsProvider.Delete(viper.GetString("domain"), id)
 
 		if err != nil {
 			throwError(err)
 		}
 
-		switch viper.GetString("format") {
+		switch outputFormat() {
 		case formatJson:
 			fmt.Print(resp.Json)
 		case formatList:
 			fmt.Print("Record successfully deleted\n\n")
 		default:
-			throwError(errors.New(fmt.Sprintf(`Unknown output format "%s".`, viper.GetString("format"))))
+			throwError(usageError(`Unknown output format "%s".`, viper.GetString("format")))
 		}
 	},
 }
@@ -62,4 +64,5 @@ func init() {
 	viper.SetDefault("format", formatList)
 
 	deleteCmd.Flags().IntVarP(&id, "id", "i", 0, "ID of the record")
+	addConditionFlags(deleteCmd)
 }
@@ -0,0 +1,166 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/lexty/yandex-dns-cli-manager/provider"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	checkOK   = "[ OK ]"
+	checkWarn = "[WARN]"
+	checkFail = "[FAIL]"
+)
+
+var checkProblems int
+
+// settingsCheckCmd represents the settings check command
+var settingsCheckCmd = &cobra.Command{
+	Use:     "check",
+	Aliases: []string{"doctor"},
+	Short:   "Check the settings against the API",
+	Long: `Validates the config file, its permissions and the domain names,
+then verifies that the admin token allows access to every configured domain.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkProblems = 0
+
+		filename := configFilepath()
+		if _, err := readConfigFile(); err != nil {
+			reportCheck(checkFail, "%s", err)
+		} else if fi, err := os.Stat(filename); os.IsNotExist(err) {
+			reportCheck(checkWarn, "config file %s does not exist", filename)
+		} else {
+			reportCheck(checkOK, "config file %s is valid", filename)
+			checkPermissions(filename, fi)
+		}
+		if fi, err := os.Stat(tokenStorePath()); err == nil {
+			checkPermissions(tokenStorePath(), fi)
+		}
+
+		domains := toStringSlice(viper.Get(cfgKeyDomains))
+		if domain := viper.GetString("domain"); domain != "" && !containsString(domains, domain) {
+			domains = append([]string{domain}, domains...)
+		}
+		if len(domains) == 0 {
+			reportCheck(checkFail, "no domain is configured")
+		}
+		for _, domain := range domains {
+			for _, problem := range domainProblems(domain) {
+				reportCheck(checkWarn, "domain %s: %s", domain, problem)
+			}
+		}
+
+		if err := loadAdminToken(); err != nil {
+			reportCheck(checkFail, "admin token: %s", err)
+		} else if p, err := provider.New(providerName(), providerSetting); err != nil {
+			if err == provider.ErrNoToken {
+				reportCheck(checkFail, "admin token is not set (%s)", apiErrorHints["no_token"])
+			} else {
+				reportCheck(checkFail, "provider %s: %s", providerName(), err)
+			}
+		} else {
+			if p.Capabilities().RequiresToken {
+				reportCheck(checkOK, "admin token %s from %s", maskToken(viper.GetString("admin-token")), settingSource("admin-token"))
+			}
+			for _, domain := range domains {
+				checkDomainAccess(p, domain)
+			}
+		}
+
+		if checkProblems > 0 {
+			throwError(cliError{exitUsage, errorCodeUsage, fmt.Sprintf("%d problem(s) found", checkProblems), ""})
+		}
+	},
+}
+
+func reportCheck(status, format string, args ...interface{}) {
+	if status == checkFail {
+		checkProblems++
+	}
+	fmt.Printf("%s %s\n", status, fmt.Sprintf(format, args...))
+}
+
+func checkPermissions(filename string, fi os.FileInfo) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if perm := fi.Mode().Perm(); perm&0077 != 0 {
+		reportCheck(checkWarn, "%s is accessible by other users (%04o), run: chmod 600 %s", filename, perm, filename)
+	}
+}
+
+func checkDomainAccess(p provider.Provider, domain string) {
+	list, err := p.List(domain)
+	if err != nil {
+		out := newErrorOutput(err)
+		if out.Hint != "" {
+			reportCheck(checkFail, "domain %s: access denied: %s (%s)", domain, out.Message, out.Hint)
+		} else {
+			reportCheck(checkFail, "domain %s: access denied: %s", domain, out.Message)
+		}
+		return
+	}
+	reportCheck(checkOK, "domain %s: access allowed, %d records", domain, len(list.Records))
+}
+
+// domainProblems detects the common mistakes in the domain name.
+func domainProblems(domain string) []string {
+	var problems []string
+	if strings.Contains(domain, "://") || strings.Contains(domain, "/") {
+		problems = append(problems, "must be a domain name, not a URL")
+	}
+	if strings.HasPrefix(strings.ToLower(domain), "www.") {
+		problems = append(problems, `must not include "www.", it is a subdomain`)
+	}
+	if strings.HasSuffix(domain, ".") {
+		problems = append(problems, "must not end with a dot")
+	}
+	if strings.ContainsAny(domain, " \t") {
+		problems = append(problems, "must not contain spaces")
+	}
+	if domain != strings.ToLower(domain) {
+		problems = append(problems, "should be lower case")
+	}
+	if !strings.Contains(strings.Trim(domain, "."), ".") {
+		problems = append(problems, "looks like a top level domain")
+	}
+	return problems
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	settingsCmd.AddCommand(settingsCheckCmd)
+}
@@ -23,7 +23,6 @@ package cmd
 import (
 	"fmt"
 
-	"errors"
 	"strings"
 
 	"github.com/lexty/yandex-dns-cli-manager/api"
@@ -35,14 +34,27 @@ import (
 var editCmd = &cobra.Command{
 	Use:   "edit",
 	Short: "Edit DNS record",
+	Long:  recordFlagsHelp,
 	Run: func(cmd *cobra.Command, args []string) {
-		resp, err := api.EditRecord(&rec, viper.GetString("domain"), viper.GetString("admin-token"))
+		if rec.RecordId == 0 {
+			throwError(usageError("--id is required."))
+		}
+		checkRequiredSettings()
+		defer lockDomain(viper.GetString("domain"))()
+		if anyFlagChanged(cmd, sortedTypeFlags()...) || anyFlagChanged(cmd, "if-content", "if-ttl", "if-match") {
+			current := currentRecord(viper.GetString("domain"), rec.RecordId)
+			checkConditions(cmd, current)
+			// the type-specific flags are checked against the type of the edited record
+			checkRecordFlags(cmd, current.RecordType)
+		}
+		rec.Fields = editedFields(cmd)
+		resp, err := dnsProvider.Update(viper.GetString("domain"), &rec)
 
 		if err != nil {
 			throwError(err)
 		}
 
-		switch viper.GetString("format") {
+		switch outputFormat() {
 		case formatJson:
 			fmt.Print(resp.Json)
 		case formatList:
@@ -50,7 +62,7 @@ var editCmd = &cobra.Command{
 			fmt.Print("Record successfully changed\n\n")
 			printList(filterRecords([]api.Record{resp.Record}, []string{"*"}), strings.Join([]string{propId, propType, propContent, propSubdomain, propPriority, propTTL, propFQDN}, ","))
 		default:
-			throwError(errors.New(fmt.Sprintf(`Unknown output format "%s".`, viper.GetString("format"))))
+			throwError(usageError(`Unknown output format "%s".`, viper.GetString("format")))
 		}
 	},
 }
@@ -63,17 +75,21 @@ func init() {
 	viper.SetDefault("format", formatList)
 
 	editCmd.Flags().IntVarP(&rec.RecordId, "id", "i", 0, "ID of the record")
-	editCmd.Flags().StringVarP(&rec.AdminMail, "admin-mail", "m", "", "email-address of the domain's administrator")
-	editCmd.Flags().StringVarP(&rec.Content, "content", "c", "", "content of the DNS record")
-	//editCmd.Flags().StringVarP(rec.Priority.(*string), "priority", "p", "", "priority of the DNS record")
-	editCmd.Flags().IntVarP(&rec.Weight, "weight", "w", 0, "weight of the SRV-record relative to other SRV-records for the same domain with the same priority")
-	editCmd.Flags().IntVarP(&rec.Port, "port", "P", 0, "TCP or UDP port of the host that is hosting the service")
-	editCmd.Flags().StringVarP(&rec.Target, "target", "T", "", "the canonical name of the host providing the service")
-	editCmd.Flags().StringVarP(&rec.Subdomain, "subdomain", "s", "", "Name of the subdomain")
-	editCmd.Flags().IntVarP(&rec.TTL, "ttl", "l", 0, "the lifetime of the DNS record in seconds")
-	editCmd.Flags().IntVarP(&rec.Refresh, "refresh", "r", 0, "time between updates")
-	editCmd.Flags().IntVarP(&rec.Retry, "retry", "R", 0, "the time between attempts to obtain records")
-	editCmd.Flags().IntVarP(&rec.Expire, "expire", "e", 0, "time limit")
-	editCmd.Flags().IntVarP(&rec.NegCache, "neg-cache", "n", 0, "caching time")
+	addRecordFlags(editCmd)
+	addConditionFlags(editCmd)
+}
 
+// currentRecord returns the record with the ID as it is now.
+func currentRecord(domain string, id int) *api.Record {
+	list, err := dnsProvider.List(domain)
+	if err != nil {
+		throwError(err)
+	}
+	for i := range list.Records {
+		if list.Records[i].RecordId == id {
+			return &list.Records[i]
+		}
+	}
+	throwError(usageError("No record with ID %d in %s.", id, domain))
+	return nil
 }
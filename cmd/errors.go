@@ -0,0 +1,116 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/spf13/viper"
+)
+
+// Exit codes of the program
+const (
+	exitOK       = 0 // success
+	exitError    = 1 // unexpected error
+	exitUsage    = 2 // invalid flags, arguments or settings
+	exitAPI      = 3 // the API rejected the request
+	exitNetwork  = 4 // the API could not be reached
+	exitMismatch = 5 // the check failed, e.g. the nameservers do not serve the records yet
+)
+
+const (
+	errorCodeError    = "error"
+	errorCodeUsage    = "usage"
+	errorCodeAPI      = "api"
+	errorCodeNetwork  = "network"
+	errorCodeMismatch = "mismatch"
+)
+
+// hints for the error codes returned by the PDD API
+var apiErrorHints = map[string]string{
+	"no_token":      `set the admin token with "settings --admin-token" (see "get-token")`,
+	"no_auth":       `set the admin token with "settings --admin-token" (see "get-token")`,
+	"bad_token":     `the admin token is invalid, get a new one (see "get-token")`,
+	"no_domain":     `set the domain with "settings --domain" or --domain`,
+	"bad_domain":    "check the domain name, it must be registered on pdd.yandex.ru",
+	"not_allowed":   "the admin token does not grant access to this domain",
+	"no_record_id":  "pass the ID of the record with --id",
+	"bad_record_id": `check the ID of the record with "list"`,
+	"no_type":       "pass the type of the record with --type",
+	"no_content":    "pass the content of the record with --content",
+}
+
+// cliError is an error with an exit code and an optional hint for the user
+type cliError struct {
+	exitCode int
+	code     string
+	msg      string
+	hint     string
+}
+
+func (e cliError) Error() string {
+	return e.msg
+}
+
+// errorOutput is the JSON representation of an error
+type errorOutput struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Hint       string `json:"hint,omitempty"`
+	HTTPStatus int    `json:"http_status,omitempty"`
+	ExitCode   int    `json:"exit_code"`
+}
+
+func usageError(format string, args ...interface{}) error {
+	return cliError{exitUsage, errorCodeUsage, fmt.Sprintf(format, args...), ""}
+}
+
+func newErrorOutput(e error) errorOutput {
+	switch err := e.(type) {
+	case cliError:
+		return errorOutput{Code: err.code, Message: err.msg, Hint: err.hint, ExitCode: err.exitCode}
+	case api.ApiError:
+		return errorOutput{Code: errorCodeAPI, Message: err.Error(), Hint: apiErrorHints[err.Error()], HTTPStatus: err.HTTPStatus, ExitCode: exitAPI}
+	case *url.Error, net.Error:
+		return errorOutput{Code: errorCodeNetwork, Message: err.Error(), Hint: "check the network connection", ExitCode: exitNetwork}
+	}
+	return errorOutput{Code: errorCodeError, Message: e.Error(), ExitCode: exitError}
+}
+
+// throwError prints the error to stderr and terminates the program with the corresponding exit code.
+func throwError(e error) {
+	out := newErrorOutput(e)
+	if viper.GetString("format") == formatJson {
+		data, _ := json.Marshal(out)
+		fmt.Fprintln(os.Stderr, string(data))
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", out.Message)
+		if out.Hint != "" {
+			fmt.Fprintf(os.Stderr, "Hint: %s\n", out.Hint)
+		}
+	}
+	os.Exit(out.ExitCode)
+}
@@ -22,22 +22,105 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"time"
 
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/lexty/yandex-dns-cli-manager/provider"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
+const (
+	cfgKeyOAuthClientId     = "oauth-client-id"
+	cfgKeyOAuthClientSecret = "oauth-client-secret"
+)
+
+var getTokenStoreName string
+
 // getTokenCmd represents the getToken command
 var getTokenCmd = &cobra.Command{
 	Use:   "get-token",
 	Short: "Instruction for getting token",
+	Long: `For the PDD backend prints the instruction for getting the admin token.
+For the Yandex 360 backend runs the OAuth device flow of your application
+(registered on oauth.yandex.ru with the directory:manage_dns permission) and stores the token.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf(`Visit
+		if providerName() != provider.NameYandex360 {
+			fmt.Printf(`Visit
 https://tech.yandex.ru/pdd/doc/concepts/access-docpage/#access-admin
 Follow the instructions.
 `)
+			return
+		}
+
+		clientId := viper.GetString(cfgKeyOAuthClientId)
+		if clientId == "" {
+			throwError(cliError{exitUsage, errorCodeUsage, "--client-id is not set", "register an application on oauth.yandex.ru"})
+		}
+		code, err := api.RequestDeviceCode(clientId)
+		if err != nil {
+			throwError(err)
+		}
+		fmt.Fprintf(os.Stderr, "Open %s and enter the code: %s\n", code.VerificationURL, code.UserCode)
+
+		interval := time.Duration(code.Interval) * time.Second
+		if interval <= 0 {
+			interval = 5 * time.Second
+		}
+		deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+		for {
+			time.Sleep(interval)
+			token, err := api.PollOAuthToken(clientId, viper.GetString(cfgKeyOAuthClientSecret), code.DeviceCode)
+			if err == api.ErrAuthorizationPending {
+				if time.Now().After(deadline) {
+					throwError(usageError("The code has expired, run get-token again."))
+				}
+				continue
+			}
+			if err != nil {
+				throwError(err)
+			}
+			storeOAuthToken(token.AccessToken)
+			return
+		}
 	},
 }
 
+// storeOAuthToken saves the token in the token store (with --store) or in the settings.
+func storeOAuthToken(token string) {
+	if getTokenStoreName != "" {
+		store := openTokenStore()
+		if err := store.Set(getTokenStoreName, token); err != nil {
+			throwError(err)
+		}
+		if err := store.Save(); err != nil {
+			throwError(err)
+		}
+		fmt.Printf("Token \"%s\" successfully stored\n", getTokenStoreName)
+		tokenUse = true
+		useStoredToken(getTokenStoreName, "")
+		return
+	}
+
+	cfg, err := readConfigFile()
+	if err != nil {
+		throwError(err)
+	}
+	section := selectedConfigSection(cfg)
+	replaceTokenSource(section, "admin-token", token)
+	if err := writeConfigFile(cfg); err != nil {
+		throwError(err)
+	}
+	fmt.Printf("Token successfully saved in \"%s\"\n", configFilepath())
+}
+
 func init() {
 	RootCmd.AddCommand(getTokenCmd)
+
+	getTokenCmd.Flags().String("client-id", "", "OAuth application ID (Yandex 360 backend)")
+	viper.BindPFlag(cfgKeyOAuthClientId, getTokenCmd.Flags().Lookup("client-id"))
+	getTokenCmd.Flags().String("client-secret", "", "OAuth application secret (Yandex 360 backend)")
+	viper.BindPFlag(cfgKeyOAuthClientSecret, getTokenCmd.Flags().Lookup("client-secret"))
+	getTokenCmd.Flags().StringVarP(&getTokenStoreName, "store", "s", "", "save the token in the encrypted store under the name")
 }
@@ -0,0 +1,140 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lexty/yandex-dns-cli-manager/journal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var historyRecordId int
+var historyUser string
+var historySince string
+var historyUntil string
+var historyAllDomains bool
+var historyVerify bool
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the changes made through the CLI",
+	Long: `Shows the entries of the local journal of the changes made with add, edit, delete and the other commands.
+The journal is kept in $HOME/` + journalFileName + ` (the "journal" setting changes the path, "off" disables it).
+--since and --until take a date ("2016-01-02"), a time (RFC 3339) or a duration ago ("24h").
+--verify checks the hash chain of the journal and exits with code 5 when it is broken.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := journal.Open(journalPath()).Entries()
+		if err != nil {
+			throwError(err)
+		}
+		if historyVerify {
+			if err := journal.Verify(entries); err != nil {
+				throwError(cliError{exitMismatch, errorCodeMismatch, "the journal is broken: " + err.Error(), "the journal was edited or truncated outside of the CLI"})
+			}
+			fmt.Printf("The journal is intact (%d entries)\n", len(entries))
+			return
+		}
+
+		since, until := parseTimeArg(historySince), parseTimeArg(historyUntil)
+		domain := viper.GetString("domain")
+		selected := []journal.Entry{}
+		for _, e := range entries {
+			switch {
+			case !historyAllDomains && domain != "" && e.Domain != domain:
+			case historyRecordId != 0 && e.RecordId != historyRecordId:
+			case historyUser != "" && e.User != historyUser:
+			case !since.IsZero() && e.Time.Before(since):
+			case !until.IsZero() && e.Time.After(until):
+			default:
+				selected = append(selected, e)
+			}
+		}
+
+		switch viper.GetString("format") {
+		case formatJson:
+			data, err := json.MarshalIndent(selected, "", "    ")
+			if err != nil {
+				throwError(err)
+			}
+			fmt.Println(string(data))
+		case formatList, formatTable:
+			header := []string{"Seq", "Time", "User", "Profile", "Domain", "Operation", "Id", "Change"}
+			rows := make([][]string, len(selected))
+			for i, e := range selected {
+				rows[i] = []string{strconv.Itoa(e.Seq), e.Time.Format("2006-01-02 15:04:05"), e.User, e.Profile, e.Domain, e.Operation, strconv.Itoa(e.RecordId), describeChange(e)}
+			}
+			printPlainTable(header, rows)
+		default:
+			throwError(usageError(`Unknown output format "%s".`, viper.GetString("format")))
+		}
+	},
+}
+
+// describeChange shows the record before and after the change.
+func describeChange(e journal.Entry) string {
+	switch {
+	case e.Before != nil && e.After != nil:
+		return formatRecord(e.Before) + " -> " + formatRecord(e.After)
+	case e.After != nil:
+		return formatRecord(e.After)
+	case e.Before != nil:
+		return formatRecord(e.Before)
+	}
+	return ""
+}
+
+// parseTimeArg parses a date, a time or a duration ago, an empty value is the zero time.
+func parseTimeArg(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d)
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t
+		}
+	}
+	throwError(usageError(`Invalid time "%s".`, value))
+	return time.Time{}
+}
+
+func init() {
+	RootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().StringP("format", "f", "", fmt.Sprintf("format output (%s|%s)", formatTable, formatJson))
+	viper.BindPFlag("format", historyCmd.Flags().Lookup("format"))
+	viper.SetDefault("format", formatList)
+
+	historyCmd.Flags().IntVarP(&historyRecordId, "id", "i", 0, "show the changes of the record with the ID")
+	historyCmd.Flags().StringVarP(&historyUser, "user", "u", "", "show the changes made by the OS user")
+	historyCmd.Flags().StringVar(&historySince, "since", "", "show the changes made since the time")
+	historyCmd.Flags().StringVar(&historyUntil, "until", "", "show the changes made until the time")
+	historyCmd.Flags().BoolVarP(&historyAllDomains, "all-domains", "A", false, "show the changes of all domains, not only of --domain")
+	historyCmd.Flags().BoolVar(&historyVerify, "verify", false, "check the hash chain of the journal")
+}
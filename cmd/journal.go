@@ -0,0 +1,139 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/lexty/yandex-dns-cli-manager/journal"
+	"github.com/lexty/yandex-dns-cli-manager/provider"
+	"github.com/spf13/viper"
+)
+
+const (
+	cfgKeyJournal = "journal"
+
+	journalFileName = ".yandexdns.journal.jsonl"
+	journalOff      = "off"
+)
+
+// journalProvider records the successful changes made through the provider in the journal
+type journalProvider struct {
+	provider.Provider
+	journal *journal.Journal
+	undoes  int    // sequence number of the entry being undone
+	user    string // who made the change if not the OS user, e.g. the API key of "serve"
+}
+
+// withJournal wraps the provider unless the journal is switched off with the "journal" setting.
+func withJournal(p provider.Provider) provider.Provider {
+	if viper.GetString(cfgKeyJournal) == journalOff {
+		return p
+	}
+	return &journalProvider{Provider: p, journal: journal.Open(journalPath())}
+}
+
+func journalPath() string {
+	if path := viper.GetString(cfgKeyJournal); path != "" && path != journalOff {
+		return path
+	}
+	return filepath.Join(filepath.Dir(getDefaultCfgFilepath()), journalFileName)
+}
+
+func (p *journalProvider) Create(domain string, r *api.Record) (api.Response, error) {
+	resp, err := p.Provider.Create(domain, r)
+	if err == nil {
+		after := *r
+		p.record(domain, journal.OpAdd, r.RecordId, nil, &after, resp)
+	}
+	return resp, err
+}
+
+func (p *journalProvider) Update(domain string, r *api.Record) (api.Response, error) {
+	before := p.find(domain, r.RecordId)
+	resp, err := p.Provider.Update(domain, r)
+	if err == nil {
+		after := *r
+		if resp.Record.RecordId != 0 {
+			after = resp.Record
+		}
+		p.record(domain, journal.OpEdit, r.RecordId, before, &after, resp)
+	}
+	return resp, err
+}
+
+func (p *journalProvider) Delete(domain string, id int) (api.Response, error) {
+	before := p.find(domain, id)
+	resp, err := p.Provider.Delete(domain, id)
+	if err == nil {
+		p.record(domain, journal.OpDelete, id, before, nil, resp)
+	}
+	return resp, err
+}
+
+// find returns the current state of the record, nil if it cannot be read.
+func (p *journalProvider) find(domain string, id int) *api.Record {
+	list, err := p.Provider.List(domain)
+	if err != nil {
+		return nil
+	}
+	for i := range list.Records {
+		if list.Records[i].RecordId == id {
+			return &list.Records[i]
+		}
+	}
+	return nil
+}
+
+// record appends the entry, the change is already made so a failure is only reported.
+func (p *journalProvider) record(domain, operation string, id int, before, after *api.Record, resp api.Response) {
+	user := p.user
+	if user == "" {
+		user = currentUser()
+	}
+	e := journal.Entry{
+		Time:      time.Now(),
+		User:      user,
+		Profile:   activeProfileName(),
+		Domain:    domain,
+		Operation: operation,
+		RecordId:  id,
+		Before:    before,
+		After:     after,
+		Response:  resp.Json,
+		Undoes:    p.undoes,
+	}
+	if err := p.journal.Append(&e); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot write the journal %s: %s\n", p.journal.Path, err)
+	}
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,203 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+	severityOff     = "off"
+
+	cfgKeyLintRules = "lint-rules"
+)
+
+var severityLevels = map[string]int{severityOff: 0, severityInfo: 1, severityWarning: 2, severityError: 3}
+
+var lintFile string
+var lintDisable string
+var lintFailOn string
+var lintListRules bool
+
+// lintFinding is a problem found by a lint rule
+type lintFinding struct {
+	Rule      string `json:"rule"`
+	Severity  string `json:"severity"`
+	Name      string `json:"name"`
+	Type      string `json:"type,omitempty"`
+	RecordIds []int  `json:"record_ids,omitempty"`
+	Message   string `json:"message"`
+}
+
+// lintReport is the JSON output of the lint command
+type lintReport struct {
+	Domain   string        `json:"domain"`
+	Findings []lintFinding `json:"findings"`
+	Errors   int           `json:"errors"`
+	Warnings int           `json:"warnings"`
+}
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check the records for structural problems",
+	Long: `Checks the records of the domain (or of a file saved with "list --format json") with the lint rules
+and reports the problems. The severity of every rule can be changed in the "lint-rules" section of the config file,
+e.g. {"lint-rules": {"duplicate": "error", "orphaned-glue": "off"}}. The command exits with code 5 when a problem
+of the --fail-on severity or higher is found.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		severities := lintSeverities()
+		if lintListRules {
+			for _, rule := range lintRules {
+				fmt.Printf("%-20s %-8s %s\n", rule.Id, severities[rule.Id], rule.Description)
+			}
+			return
+		}
+		if _, ok := severityLevels[lintFailOn]; !ok {
+			throwError(usageError(`Unknown severity "%s".`, lintFailOn))
+		}
+
+		domain := viper.GetString("domain")
+		var records []api.Record
+		if lintFile != "" {
+			var err error
+			if records, err = readDesiredState(lintFile); err != nil {
+				throwError(err)
+			}
+			if domain == "" && len(records) > 0 {
+				domain = records[0].Domain
+			}
+		} else {
+			checkRequiredSettings()
+			list, err := dnsProvider.List(domain)
+			if err != nil {
+				throwError(err)
+			}
+			records = list.Records
+		}
+
+		report := lintReport{Domain: domain, Findings: []lintFinding{}}
+		zone := newLintZone(domain, records)
+		for _, rule := range lintRules {
+			severity := severities[rule.Id]
+			if severity == severityOff {
+				continue
+			}
+			for _, f := range rule.check(zone) {
+				f.Rule, f.Severity = rule.Id, severity
+				report.Findings = append(report.Findings, f)
+				switch severity {
+				case severityError:
+					report.Errors++
+				case severityWarning:
+					report.Warnings++
+				}
+			}
+		}
+		sort.Stable(lintFindingSorter(report.Findings))
+
+		switch viper.GetString("format") {
+		case formatJson:
+			data, err := json.MarshalIndent(report, "", "    ")
+			if err != nil {
+				throwError(err)
+			}
+			fmt.Println(string(data))
+		case formatList, formatTable:
+			for _, f := range report.Findings {
+				fmt.Printf("%-7s %-20s %s %s: %s\n", f.Severity, f.Rule, f.Name, f.Type, f.Message)
+			}
+			fmt.Printf("%d errors, %d warnings\n", report.Errors, report.Warnings)
+		default:
+			throwError(usageError(`Unknown output format "%s".`, viper.GetString("format")))
+		}
+
+		if lintFailOn != severityOff {
+			failed := 0
+			for _, f := range report.Findings {
+				if severityLevels[f.Severity] >= severityLevels[lintFailOn] {
+					failed++
+				}
+			}
+			if failed > 0 {
+				throwError(cliError{exitMismatch, errorCodeMismatch, fmt.Sprintf("%d problem(s) of the %s severity or higher found", failed, lintFailOn), "fix the records or change the severity of the rules"})
+			}
+		}
+	},
+}
+
+// lintSeverities returns the severity of every rule with the overrides of the config file and --disable applied.
+func lintSeverities() map[string]string {
+	severities := make(map[string]string, len(lintRules))
+	for _, rule := range lintRules {
+		severities[rule.Id] = rule.Severity
+	}
+	for id, severity := range viper.GetStringMapString(cfgKeyLintRules) {
+		if _, ok := severities[id]; !ok {
+			throwError(usageError(`Unknown lint rule "%s" in the config file.`, id))
+		}
+		if _, ok := severityLevels[severity]; !ok {
+			throwError(usageError(`Unknown severity "%s" of the lint rule "%s".`, severity, id))
+		}
+		severities[id] = severity
+	}
+	for _, id := range splitList(lintDisable) {
+		if _, ok := severities[id]; !ok {
+			throwError(usageError(`Unknown lint rule "%s".`, id))
+		}
+		severities[id] = severityOff
+	}
+	return severities
+}
+
+// lintFindingSorter orders the findings by severity and name
+type lintFindingSorter []lintFinding
+
+func (s lintFindingSorter) Len() int      { return len(s) }
+func (s lintFindingSorter) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s lintFindingSorter) Less(i, j int) bool {
+	if severityLevels[s[i].Severity] != severityLevels[s[j].Severity] {
+		return severityLevels[s[i].Severity] > severityLevels[s[j].Severity]
+	}
+	return s[i].Name < s[j].Name
+}
+
+func init() {
+	RootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringP("format", "f", "", fmt.Sprintf("format output (%s|%s)", formatList, formatJson))
+	viper.BindPFlag("format", lintCmd.Flags().Lookup("format"))
+	viper.SetDefault("format", formatList)
+
+	lintCmd.Flags().StringVarP(&lintFile, "file", "F", "", `check the records of a file (output of "list --format json") instead of the domain`)
+	lintCmd.Flags().StringVarP(&lintDisable, "disable", "x", "", "comma separated rules to skip")
+	lintCmd.Flags().StringVar(&lintFailOn, "fail-on", severityError, fmt.Sprintf("lowest severity making the command fail (%s|%s|%s|%s)", severityError, severityWarning, severityInfo, severityOff))
+	lintCmd.Flags().BoolVar(&lintListRules, "rules", false, "list the rules with their severities and exit")
+}
@@ -0,0 +1,262 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+)
+
+// lintRule checks the zone for one kind of problem
+type lintRule struct {
+	Id          string
+	Severity    string
+	Description string
+	check       func(z *lintZone) []lintFinding
+}
+
+// lintZone indexes the records of the domain by name
+type lintZone struct {
+	Domain  string
+	Records []api.Record
+	byName  map[string][]*api.Record
+}
+
+var srvNamePattern = regexp.MustCompile(`^_[a-z0-9-]+\._(tcp|udp|tls|sctp)(\..+)?$`)
+
+var lintRules = []lintRule{
+	{"cname-conflict", severityError, "CNAME coexists with other records at the same name", lintCnameConflict},
+	{"cname-apex", severityError, "CNAME at the apex of the domain", lintCnameApex},
+	{"mx-cname", severityError, "MX points at a CNAME", func(z *lintZone) []lintFinding { return lintTargetCname(z, typeMX) }},
+	{"ns-cname", severityError, "NS points at a CNAME", func(z *lintZone) []lintFinding { return lintTargetCname(z, typeNS) }},
+	{"duplicate", severityWarning, "the same record is defined several times", lintDuplicate},
+	{"ttl-below-minttl", severityWarning, "TTL is below the minimum TTL of the SOA record", lintTTLBelowMin},
+	{"ttl-inconsistent", severityWarning, "records of one RRset have different TTLs", lintTTLInconsistent},
+	{"srv-name", severityError, `SRV name is not in the "_service._proto" form`, lintSrvName},
+	{"orphaned-glue", severityWarning, "address record in a delegated subdomain no NS record points at", lintOrphanedGlue},
+}
+
+func newLintZone(domain string, records []api.Record) *lintZone {
+	z := &lintZone{Domain: strings.ToLower(domain), Records: records, byName: make(map[string][]*api.Record)}
+	for i := range records {
+		name := lintName(&records[i])
+		z.byName[name] = append(z.byName[name], &records[i])
+	}
+	return z
+}
+
+// names returns the names of the zone in a stable order.
+func (z *lintZone) names() []string {
+	names := make([]string, 0, len(z.byName))
+	for name := range z.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// nameOf converts a host name of the record content to the name in the zone, ok is false for outside hosts.
+func (z *lintZone) nameOf(host string) (string, bool) {
+	return relativeName(z.Domain, host)
+}
+
+func lintName(r *api.Record) string {
+	if r.Subdomain == "" {
+		return apexLabel
+	}
+	return strings.ToLower(r.Subdomain)
+}
+
+func recordIds(records []*api.Record) []int {
+	ids := make([]int, len(records))
+	for i, r := range records {
+		ids[i] = r.RecordId
+	}
+	return ids
+}
+
+func lintCnameConflict(z *lintZone) []lintFinding {
+	var findings []lintFinding
+	for _, name := range z.names() {
+		records := z.byName[name]
+		cnames := 0
+		for _, r := range records {
+			if strings.ToUpper(r.RecordType) == typeCNAME {
+				cnames++
+			}
+		}
+		if cnames > 0 && len(records) > 1 {
+			findings = append(findings, lintFinding{Name: name, Type: typeCNAME, RecordIds: recordIds(records),
+				Message: fmt.Sprintf("CNAME cannot coexist with the other %d records of the name", len(records)-1)})
+		}
+	}
+	return findings
+}
+
+func lintCnameApex(z *lintZone) []lintFinding {
+	var findings []lintFinding
+	for _, r := range z.byName[apexLabel] {
+		if strings.ToUpper(r.RecordType) == typeCNAME {
+			findings = append(findings, lintFinding{Name: apexLabel, Type: typeCNAME, RecordIds: []int{r.RecordId},
+				Message: "CNAME is not allowed at the apex, the SOA and NS records live there"})
+		}
+	}
+	return findings
+}
+
+func lintTargetCname(z *lintZone, recordType string) []lintFinding {
+	var findings []lintFinding
+	for i := range z.Records {
+		r := &z.Records[i]
+		if strings.ToUpper(r.RecordType) != recordType {
+			continue
+		}
+		target, ok := z.nameOf(r.Content)
+		if !ok {
+			continue
+		}
+		for _, t := range z.byName[target] {
+			if strings.ToUpper(t.RecordType) == typeCNAME {
+				findings = append(findings, lintFinding{Name: lintName(r), Type: recordType, RecordIds: []int{r.RecordId, t.RecordId},
+					Message: fmt.Sprintf("%s points at %s which is a CNAME", recordType, r.Content)})
+			}
+		}
+	}
+	return findings
+}
+
+func lintDuplicate(z *lintZone) []lintFinding {
+	var findings []lintFinding
+	byKey := make(map[string][]*api.Record)
+	var keys []string
+	for i := range z.Records {
+		key := recordKey(&z.Records[i])
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], &z.Records[i])
+	}
+	for _, key := range keys {
+		if records := byKey[key]; len(records) > 1 {
+			findings = append(findings, lintFinding{Name: lintName(records[0]), Type: strings.ToUpper(records[0].RecordType), RecordIds: recordIds(records),
+				Message: fmt.Sprintf(`"%s" is defined %d times`, records[0].Content, len(records))})
+		}
+	}
+	return findings
+}
+
+func lintTTLBelowMin(z *lintZone) []lintFinding {
+	minTTL := 0
+	for _, r := range z.byName[apexLabel] {
+		if strings.ToUpper(r.RecordType) == typeSOA {
+			minTTL = r.MinTTL
+		}
+	}
+	if minTTL == 0 {
+		return nil
+	}
+	var findings []lintFinding
+	for i := range z.Records {
+		r := &z.Records[i]
+		if r.TTL != 0 && r.TTL < minTTL && strings.ToUpper(r.RecordType) != typeSOA {
+			findings = append(findings, lintFinding{Name: lintName(r), Type: strings.ToUpper(r.RecordType), RecordIds: []int{r.RecordId},
+				Message: fmt.Sprintf("TTL %d is below the SOA minimum TTL %d", r.TTL, minTTL)})
+		}
+	}
+	return findings
+}
+
+func lintTTLInconsistent(z *lintZone) []lintFinding {
+	var findings []lintFinding
+	for _, name := range z.names() {
+		byType := make(map[string][]*api.Record)
+		var types []string
+		for _, r := range z.byName[name] {
+			t := strings.ToUpper(r.RecordType)
+			if _, ok := byType[t]; !ok {
+				types = append(types, t)
+			}
+			byType[t] = append(byType[t], r)
+		}
+		for _, t := range types {
+			records := byType[t]
+			for _, r := range records[1:] {
+				if r.TTL != records[0].TTL {
+					findings = append(findings, lintFinding{Name: name, Type: t, RecordIds: recordIds(records),
+						Message: "the records of the RRset have different TTLs, resolvers use the lowest one"})
+					break
+				}
+			}
+		}
+	}
+	return findings
+}
+
+func lintSrvName(z *lintZone) []lintFinding {
+	var findings []lintFinding
+	for i := range z.Records {
+		r := &z.Records[i]
+		if strings.ToUpper(r.RecordType) == typeSRV && !srvNamePattern.MatchString(lintName(r)) {
+			findings = append(findings, lintFinding{Name: lintName(r), Type: typeSRV, RecordIds: []int{r.RecordId},
+				Message: `the name must be "_service._proto", e.g. "_sip._tcp"`})
+		}
+	}
+	return findings
+}
+
+func lintOrphanedGlue(z *lintZone) []lintFinding {
+	delegated := make(map[string]bool)
+	nsTargets := make(map[string]bool)
+	for i := range z.Records {
+		r := &z.Records[i]
+		if strings.ToUpper(r.RecordType) != typeNS {
+			continue
+		}
+		if name := lintName(r); name != apexLabel {
+			delegated[name] = true
+		}
+		if target, ok := z.nameOf(r.Content); ok {
+			nsTargets[target] = true
+		}
+	}
+
+	var findings []lintFinding
+	for i := range z.Records {
+		r := &z.Records[i]
+		t := strings.ToUpper(r.RecordType)
+		name := lintName(r)
+		if (t != typeA && t != typeAAAA) || nsTargets[name] {
+			continue
+		}
+		for d := range delegated {
+			if name == d || strings.HasSuffix(name, "."+d) {
+				findings = append(findings, lintFinding{Name: name, Type: t, RecordIds: []int{r.RecordId},
+					Message: fmt.Sprintf("the record is inside the delegated subdomain %s and no NS record points at it", d)})
+				break
+			}
+		}
+	}
+	return findings
+}
@@ -23,36 +23,32 @@ package cmd
 import (
 	"fmt"
 
-	"os"
+	"sort"
 
 	"strconv"
 
 	"strings"
 
-	"errors"
-
-	"log"
-
 	"github.com/lexty/yandex-dns-cli-manager/api"
-	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 const (
-	propAll       = "*"
-	propId        = "id"
-	propSubdomain = "subdomain"
-	propType      = "type"
-	propContent   = "content"
-	propPriority  = "priority"
-	propTTL       = "ttl"
-	propFQDN      = "fqdn"
-	propAdminMail = "admin_mail"
-	propRetry     = "retry"
-	propRefresh   = "refresh"
-	propExpire    = "expire"
-	propMinTTL    = "minttl"
+	propAll         = "*"
+	propId          = "id"
+	propSubdomain   = "subdomain"
+	propType        = "type"
+	propContent     = "content"
+	propPriority    = "priority"
+	propTTL         = "ttl"
+	propFQDN        = "fqdn"
+	propAdminMail   = "admin_mail"
+	propRetry       = "retry"
+	propRefresh     = "refresh"
+	propExpire      = "expire"
+	propMinTTL      = "minttl"
+	propFingerprint = "fingerprint"
 
 	propsDefault = propId + "," + propSubdomain + "," + propType + "," + propContent + "," + propPriority
 
@@ -69,6 +65,7 @@ const (
 	formatList  = "list"
 	formatTable = "table"
 	formatJson  = "json"
+	formatTree  = "tree"
 )
 
 var props map[string]string
@@ -77,15 +74,8 @@ var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "The list of the DNS records",
 	Run: func(cmd *cobra.Command, args []string) {
-		if !viper.IsSet("admin-token") {
-			fmt.Println("Error: --admin-token is not set")
-			os.Exit(-1)
-		}
-		if !viper.IsSet("domain") {
-			fmt.Println("Error: --domain is not set")
-			os.Exit(-1)
-		}
-		list, err := api.GetList(viper.GetString("domain"), viper.GetString("admin-token"))
+		checkRequiredSettings()
+		list, err := dnsProvider.List(viper.GetString("domain"))
 		if err != nil {
 			throwError(err)
 		}
@@ -99,20 +89,17 @@ var listCmd = &cobra.Command{
 
 		props := viper.GetString("props")
 		if props == propAll {
-			props = strings.Join([]string{propId, propType, propContent, propSubdomain, propPriority, propTTL, propFQDN, propAdminMail, propRetry, propRefresh, propExpire, propMinTTL}, ",")
+			props = strings.Join([]string{propId, propType, propContent, propSubdomain, propPriority, propTTL, propFQDN, propAdminMail, propRetry, propRefresh, propExpire, propMinTTL, propFingerprint}, ",")
 		}
 		setProps()
+		if sortKeys := viper.GetString("sort"); sortKeys != "" {
+			sortRecords(list.Records, sortKeys)
+		}
 
 		printResponse(list, viper.GetString("format"), props, types)
 	},
 }
 
-func throwError(e error) {
-	panic(e)
-	log.Fatalf("Error: %s", e.Error())
-	os.Exit(-1)
-}
-
 func parseCommaSep(raw string) []string {
 	parts := strings.Split(raw, ",")
 	for i, part := range parts {
@@ -135,31 +122,7 @@ func setProps() {
 	props[propRefresh] = "Refresh"
 	props[propExpire] = "Expire"
 	props[propMinTTL] = "MinTTL"
-}
-
-func printTable(records []*api.Record, props string) {
-	parsedProps := parseCommaSep(props)
-	header := make([]string, len(parsedProps))
-	var err error
-	for i, prop := range parsedProps {
-		if header[i], err = getHeader(prop); err != nil {
-			throwError(err)
-		}
-	}
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader(header)
-
-	for _, rec := range records {
-		data := make([]string, len(parsedProps))
-		for q, prop := range parsedProps {
-			if data[q], err = getValue(prop, rec); err != nil {
-				throwError(err)
-			}
-		}
-		table.Append(data)
-	}
-
-	table.Render()
+	props[propFingerprint] = "Fingerprint"
 }
 
 func printList(records []*api.Record, props string) {
@@ -186,6 +149,18 @@ func printList(records []*api.Record, props string) {
 	}
 }
 
+// outputFormat returns the format of the commands printing a message or the JSON response.
+// The formats of list (table, tree) print the message, so a default format taken from a profile
+// or the config file does not make the command fail after the change is made.
+func outputFormat() string {
+	switch format := viper.GetString("format"); format {
+	case formatTable, formatTree:
+		return formatList
+	default:
+		return format
+	}
+}
+
 func printResponse(response api.Response, format, props string, types []string) {
 	switch format {
 	case formatJson:
@@ -194,11 +169,48 @@ func printResponse(response api.Response, format, props string, types []string)
 		printList(filterRecords(response.Records, types), props)
 	case formatTable:
 		printTable(filterRecords(response.Records, types), props)
+	case formatTree:
+		printTree(filterRecords(response.Records, types), viper.GetString("domain"))
 	default:
-		throwError(errors.New(fmt.Sprintf(`Unknown output format "%s".`, format)))
+		throwError(usageError(`Unknown output format "%s".`, format))
 	}
 }
 
+// recordSorter sorts the records by the comma separated properties, "-" before a property reverses the order
+type recordSorter struct {
+	records []api.Record
+	keys    []string
+}
+
+func (s recordSorter) Len() int      { return len(s.records) }
+func (s recordSorter) Swap(i, j int) { s.records[i], s.records[j] = s.records[j], s.records[i] }
+func (s recordSorter) Less(i, j int) bool {
+	for _, key := range s.keys {
+		desc := strings.HasPrefix(key, "-")
+		prop := strings.ToLower(strings.TrimPrefix(key, "-"))
+		a, err := getValue(prop, &s.records[i])
+		if err != nil {
+			throwError(err)
+		}
+		b, _ := getValue(prop, &s.records[j])
+		if a == b {
+			continue
+		}
+		less := a < b
+		if na, errA := strconv.Atoi(a); errA == nil {
+			if nb, errB := strconv.Atoi(b); errB == nil {
+				less = na < nb
+			}
+		}
+		return less != desc
+	}
+	return false
+}
+
+func sortRecords(records []api.Record, keys string) {
+	sort.Stable(recordSorter{records, parseCommaSep(keys)})
+}
+
 func filterRecords(recs []api.Record, types []string) []*api.Record {
 	var filteredRecs []*api.Record
 	for i, rec := range recs {
@@ -222,7 +234,7 @@ func getHeader(prop string) (string, error) {
 	if title, ok := props[strings.ToLower(prop)]; ok {
 		return title, nil
 	} else {
-		return "", errors.New(fmt.Sprintf(`Unknown record property "%s".`, prop))
+		return "", usageError(`Unknown record property "%s".`, prop)
 	}
 }
 func getValue(prop string, r *api.Record) (string, error) {
@@ -239,7 +251,7 @@ func getValue(prop string, r *api.Record) (string, error) {
 	case propTTL:
 		val = strconv.Itoa(r.TTL)
 	case propPriority:
-		val = fmt.Sprintf("%v", r.Priority)
+		val = api.PriorityString(r.Priority)
 	case propFQDN:
 		val = r.FQDN
 	case propAdminMail:
@@ -252,8 +264,10 @@ func getValue(prop string, r *api.Record) (string, error) {
 		val = strconv.Itoa(r.Expire)
 	case propMinTTL:
 		val = strconv.Itoa(r.MinTTL)
+	case propFingerprint:
+		val = recordFingerprint(r)
 	default:
-		return "", errors.New(fmt.Sprintf(`Unknown record property %s.`, prop))
+		return "", usageError(`Unknown record property "%s".`, prop)
 	}
 	return val, nil
 }
@@ -261,15 +275,23 @@ func getValue(prop string, r *api.Record) (string, error) {
 func init() {
 	RootCmd.AddCommand(listCmd)
 
-	listCmd.Flags().StringP("format", "f", "", fmt.Sprintf("format output (%s|%s|%s)", formatList, formatTable, formatJson))
+	listCmd.Flags().StringP("format", "f", "", fmt.Sprintf("format output (%s|%s|%s|%s)", formatList, formatTable, formatTree, formatJson))
 	viper.BindPFlag("format", listCmd.Flags().Lookup("format"))
 	viper.SetDefault("format", formatList)
 
-	listCmd.Flags().StringP("props", "p", "", fmt.Sprintf("comma separated record properties for display (available: %s) (does not work for json format)", strings.Join([]string{propAll, propId, propType, propContent, propSubdomain, propPriority, propTTL, propFQDN, propAdminMail, propRetry, propRefresh, propExpire, propMinTTL}, ", ")))
+	listCmd.Flags().StringP("props", "p", "", fmt.Sprintf("comma separated record properties for display (available: %s) (does not work for json format)", strings.Join([]string{propAll, propId, propType, propContent, propSubdomain, propPriority, propTTL, propFQDN, propAdminMail, propRetry, propRefresh, propExpire, propMinTTL, propFingerprint}, ", ")))
 	viper.BindPFlag("props", listCmd.Flags().Lookup("props"))
 	viper.SetDefault("props", propsDefault)
 
 	listCmd.Flags().StringP("types", "t", "", fmt.Sprintf("comma separated record types for display (available: %s) (does not work for json format)", strings.Join([]string{typeAll, typeA, typeAAAA, typeCNAME, typeSRV, typeTXT, typeSOA, typeMX, typeNS}, ", ")))
 	viper.BindPFlag("types", listCmd.Flags().Lookup("types"))
 	viper.SetDefault("types", "*")
+
+	listCmd.Flags().StringP("sort", "s", "", "comma separated record properties to sort by, prefix a property with \"-\" for descending order")
+	viper.BindPFlag("sort", listCmd.Flags().Lookup("sort"))
+
+	listCmd.Flags().BoolVarP(&tableWide, "wide", "w", false, "do not truncate wide columns in table format")
+	listCmd.Flags().BoolVar(&tableWrap, "wrap", false, "wrap wide columns instead of truncating them in table format")
+	listCmd.Flags().BoolVar(&tableNoHeaders, "no-headers", false, "do not print headers in table format")
+	listCmd.Flags().StringVar(&tableColor, "color", colorAuto, fmt.Sprintf("colorize record types in table format (%s|%s|%s)", colorAuto, colorAlways, colorNever))
 }
@@ -0,0 +1,306 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/lexty/yandex-dns-cli-manager/dnsclient"
+	"github.com/lexty/yandex-dns-cli-manager/mailauth"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var mailSubdomain string
+var mailSave bool
+var mailTTL int
+var mailResolver string
+
+var spfBuild mailauth.SPF
+var spfIP4, spfIP6, spfInclude string
+
+var dkimSelector string
+var dkimKey string
+var dkimKeyFile string
+var dkimHashes string
+var dkimTesting bool
+
+var dmarcBuild mailauth.DMARC
+var dmarcRUA, dmarcRUF string
+
+// mailCmd represents the mail command
+var mailCmd = &cobra.Command{
+	Use:   "mail",
+	Short: "Build and analyze the SPF, DKIM and DMARC records",
+	Long: `Without the record flags the subcommands explain the existing records and report their problems.
+With the record flags they build a new record, check it and, with --save, add it or replace the existing one.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var mailSpfCmd = &cobra.Command{
+	Use:   "spf",
+	Short: "Build or analyze the SPF record",
+	Long: `Analyzes the SPF record including the DNS lookup limit of the nested records or builds a new one, e.g.
+  mail spf --mx --include _spf.yandex.net --all -all --save`,
+	Run: func(cmd *cobra.Command, args []string) {
+		built := ""
+		if anyFlagChanged(cmd, "a", "mx", "ip4", "ip6", "include", "redirect", "all") {
+			spfBuild.IP4 = splitList(spfIP4)
+			spfBuild.IP6 = splitList(spfIP6)
+			spfBuild.Include = splitList(spfInclude)
+			if spfBuild.Redirect != "" && !cmd.Flags().Lookup("all").Changed {
+				spfBuild.All = ""
+			}
+			built = spfBuild.String()
+		}
+		runMailRecord("SPF", mailRecordName(""), built, mailauth.IsSPF, func(record string) mailauth.Analysis {
+			a := mailauth.AnalyzeSPF(record)
+			if !a.HasErrors() {
+				count, problems := mailauth.CountSPFLookups(record, lookupTXT)
+				a.Problems = append(a.Problems, problems...)
+				a.Terms = append(a.Terms, mailauth.Term{Text: "", Explanation: fmt.Sprintf("%d of %d DNS lookups", count, mailauth.SPFLookupLimit)})
+				if count > mailauth.SPFLookupLimit {
+					a.Problems = append(a.Problems, mailauth.Problem{Severity: mailauth.SeverityError, Message: fmt.Sprintf("the record needs %d DNS lookups, more than %d allowed", count, mailauth.SPFLookupLimit)})
+				}
+			}
+			return a
+		})
+	},
+}
+
+var mailDkimCmd = &cobra.Command{
+	Use:   "dkim",
+	Short: "Build or analyze the DKIM key record",
+	Long: `Analyzes the DKIM key record of the selector or builds a new one from a public key, e.g.
+  mail dkim --selector mail --key-file dkim.pub --save`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if dkimSelector == "" {
+			throwError(usageError("--selector is required."))
+		}
+		built := ""
+		if dkimKey != "" || dkimKeyFile != "" {
+			d := mailauth.DKIM{PublicKey: dkimKey, Hashes: splitList(dkimHashes), Testing: dkimTesting}
+			if dkimKeyFile != "" {
+				data, err := ioutil.ReadFile(dkimKeyFile)
+				if err != nil {
+					throwError(err)
+				}
+				if d.KeyType, d.PublicKey, err = mailauth.DKIMPublicKey(data); err != nil {
+					throwError(usageError(`Cannot read the public key "%s": %s`, dkimKeyFile, err))
+				}
+			}
+			built = d.String()
+		}
+		runMailRecord("DKIM", mailRecordName(dkimSelector+"._domainkey"), built, mailauth.IsDKIM, mailauth.AnalyzeDKIM)
+	},
+}
+
+var mailDmarcCmd = &cobra.Command{
+	Use:   "dmarc",
+	Short: "Build or analyze the DMARC record",
+	Long: `Analyzes the DMARC record and reports the weak policies or builds a new one, e.g.
+  mail dmarc --policy quarantine --rua dmarc@example.com --save`,
+	Run: func(cmd *cobra.Command, args []string) {
+		built := ""
+		if anyFlagChanged(cmd, "policy", "sp", "pct", "rua", "ruf", "adkim", "aspf", "fo") {
+			if dmarcBuild.Policy == "" {
+				throwError(usageError("--policy is required to build the record."))
+			}
+			dmarcBuild.RUA = splitList(dmarcRUA)
+			dmarcBuild.RUF = splitList(dmarcRUF)
+			built = dmarcBuild.String()
+		}
+		runMailRecord("DMARC", mailRecordName("_dmarc"), built, mailauth.IsDMARC, mailauth.AnalyzeDMARC)
+	},
+}
+
+// runMailRecord analyzes the built record or the existing records of the kind at the name
+// and saves the built record when requested.
+func runMailRecord(kind, name, built string, is func(string) bool, analyze func(string) mailauth.Analysis) {
+	checkRequiredSettings()
+	domain := viper.GetString("domain")
+	if mailSave {
+		defer lockDomain(domain)()
+	}
+	list, err := dnsProvider.List(domain)
+	if err != nil {
+		throwError(err)
+	}
+	var existing []api.Record
+	for _, r := range list.Records {
+		subdomain := r.Subdomain
+		if subdomain == "" {
+			subdomain = apexLabel
+		}
+		if strings.EqualFold(subdomain, name) && strings.ToUpper(r.RecordType) == typeTXT && is(r.Content) {
+			existing = append(existing, r)
+		}
+	}
+
+	if built != "" {
+		a := analyze(built)
+		printMailAnalysis(built, a)
+		if !mailSave {
+			return
+		}
+		if a.HasErrors() {
+			throwError(usageError("The record has errors and is not saved."))
+		}
+		saveMailRecord(domain, name, built, existing)
+		fmt.Printf("%s record of %s saved\n", kind, name)
+		return
+	}
+
+	if len(existing) == 0 {
+		throwError(cliError{exitMismatch, errorCodeMismatch, fmt.Sprintf("no %s record at %s", kind, name), "build one with the flags of the command, see --help"})
+	}
+	failed := false
+	for _, r := range existing {
+		a := analyze(r.Content)
+		if len(existing) > 1 {
+			a.Problems = append(a.Problems, mailauth.Problem{Severity: mailauth.SeverityError, Message: fmt.Sprintf("%d %s records at %s, receivers treat it as an error", len(existing), kind, name)})
+		}
+		printMailAnalysis(r.Content, a)
+		failed = failed || a.HasErrors()
+	}
+	if failed {
+		throwError(cliError{exitMismatch, errorCodeMismatch, fmt.Sprintf("the %s record has errors", kind), ""})
+	}
+}
+
+func printMailAnalysis(record string, a mailauth.Analysis) {
+	fmt.Println(record)
+	for _, t := range a.Terms {
+		fmt.Printf("  %-32s %s\n", t.Text, t.Explanation)
+	}
+	for _, p := range a.Problems {
+		fmt.Printf("  %s: %s\n", p.Severity, p.Message)
+	}
+	fmt.Println()
+}
+
+// saveMailRecord replaces the only existing record or adds a new one.
+func saveMailRecord(domain, name, content string, existing []api.Record) {
+	var err error
+	switch len(existing) {
+	case 0:
+		r := api.Record{RecordType: typeTXT, Subdomain: name, Content: content, TTL: mailTTL}
+		_, err = dnsProvider.Create(domain, &r)
+	case 1:
+		r := existing[0]
+		r.Content = content
+		if mailTTL != 0 {
+			r.TTL = mailTTL
+		}
+		_, err = dnsProvider.Update(domain, &r)
+	default:
+		throwError(usageError("There are %d records at %s, delete the duplicates first.", len(existing), name))
+	}
+	if err != nil {
+		throwError(err)
+	}
+}
+
+// mailRecordName returns the subdomain of the record, e.g. "_dmarc" or "_dmarc.mail" with --subdomain mail.
+func mailRecordName(prefix string) string {
+	subdomain := mailSubdomain
+	if subdomain == apexLabel {
+		subdomain = ""
+	}
+	switch {
+	case prefix == "" && subdomain == "":
+		return apexLabel
+	case prefix == "":
+		return subdomain
+	case subdomain == "":
+		return prefix
+	}
+	return prefix + "." + subdomain
+}
+
+// lookupTXT returns the TXT records from the --resolver or the system resolver.
+func lookupTXT(name string) ([]string, error) {
+	if mailResolver == "" {
+		return net.LookupTXT(name)
+	}
+	client := &dnsclient.Client{Timeout: 5 * time.Second, Recursion: true}
+	return client.Lookup(mailResolver, name, dnsclient.TypeTXT)
+}
+
+func anyFlagChanged(cmd *cobra.Command, names ...string) bool {
+	for _, name := range names {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits a comma separated value skipping the empty items.
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range parseCommaSep(raw) {
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func init() {
+	RootCmd.AddCommand(mailCmd)
+	mailCmd.AddCommand(mailSpfCmd, mailDkimCmd, mailDmarcCmd)
+
+	mailCmd.PersistentFlags().StringVarP(&mailSubdomain, "subdomain", "s", "", "subdomain sending the mail (default is the domain itself)")
+	mailCmd.PersistentFlags().BoolVar(&mailSave, "save", false, "add the built record or replace the existing one")
+	mailCmd.PersistentFlags().IntVarP(&mailTTL, "ttl", "l", 0, "the lifetime of the saved record in seconds")
+
+	mailSpfCmd.Flags().BoolVar(&spfBuild.A, "a", false, "allow the A/AAAA addresses of the domain")
+	mailSpfCmd.Flags().BoolVar(&spfBuild.MX, "mx", false, "allow the mail exchangers of the domain")
+	mailSpfCmd.Flags().StringVar(&spfIP4, "ip4", "", "comma separated IPv4 addresses or networks to allow")
+	mailSpfCmd.Flags().StringVar(&spfIP6, "ip6", "", "comma separated IPv6 addresses or networks to allow")
+	mailSpfCmd.Flags().StringVarP(&spfInclude, "include", "i", "", "comma separated domains whose SPF records are included, e.g. _spf.yandex.net")
+	mailSpfCmd.Flags().StringVar(&spfBuild.Redirect, "redirect", "", "domain whose SPF record is used instead")
+	mailSpfCmd.Flags().StringVar(&spfBuild.All, "all", "~all", "result for other servers (-all|~all|?all)")
+	mailSpfCmd.Flags().StringVarP(&mailResolver, "resolver", "r", "", "resolver (host[:port]) used to count the DNS lookups (default is the system resolver)")
+
+	mailDkimCmd.Flags().StringVarP(&dkimSelector, "selector", "k", "", "DKIM selector, the record is <selector>._domainkey")
+	mailDkimCmd.Flags().StringVar(&dkimKey, "key", "", "base64 encoded public key")
+	mailDkimCmd.Flags().StringVar(&dkimKeyFile, "key-file", "", "PEM encoded public key file (RSA or Ed25519)")
+	mailDkimCmd.Flags().StringVar(&dkimHashes, "hash", "", "comma separated allowed hash algorithms, e.g. sha256")
+	mailDkimCmd.Flags().BoolVar(&dkimTesting, "testing", false, "mark the key as being tested")
+
+	mailDmarcCmd.Flags().StringVarP(&dmarcBuild.Policy, "policy", "p", "", "policy for the domain (none|quarantine|reject)")
+	mailDmarcCmd.Flags().StringVar(&dmarcBuild.SubdomainPolicy, "sp", "", "policy for the subdomains")
+	mailDmarcCmd.Flags().IntVar(&dmarcBuild.Percent, "pct", 0, "percent of the failing messages the policy applies to")
+	mailDmarcCmd.Flags().StringVar(&dmarcRUA, "rua", "", "comma separated addresses receiving the aggregate reports")
+	mailDmarcCmd.Flags().StringVar(&dmarcRUF, "ruf", "", "comma separated addresses receiving the failure reports")
+	mailDmarcCmd.Flags().StringVar(&dmarcBuild.ADKIM, "adkim", "", "DKIM alignment (r|s)")
+	mailDmarcCmd.Flags().StringVar(&dmarcBuild.ASPF, "aspf", "", "SPF alignment (r|s)")
+	mailDmarcCmd.Flags().StringVar(&dmarcBuild.FailureOptions, "fo", "", "failure report options, e.g. 1")
+}
@@ -0,0 +1,226 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var moveRecursive bool
+var moveKeep bool
+var moveYes bool
+var moveDryRun bool
+
+// moveCmd represents the move command
+var moveCmd = &cobra.Command{
+	Use:     "move <subdomain> <new-subdomain>",
+	Aliases: []string{"rename"},
+	Short:   "Rename a subdomain with all its records",
+	Long: `Copies all records of the subdomain to the new name, points the CNAME records of the domain
+at the new name and deletes the original records once all copies are created.
+With --recursive the deeper subdomains are moved as well, e.g. "api.staging" to "api.stage".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 2 {
+			throwError(usageError("The subdomain and the new subdomain are required."))
+		}
+		from, to := strings.ToLower(args[0]), strings.ToLower(args[1])
+		if from == apexLabel || to == apexLabel || from == "" || to == "" {
+			throwError(usageError("The domain itself cannot be moved."))
+		}
+		if from == to {
+			throwError(usageError("The subdomains are the same."))
+		}
+		checkRequiredSettings()
+		domain := viper.GetString("domain")
+		defer lockDomain(domain)()
+		list, err := dnsProvider.List(domain)
+		if err != nil {
+			throwError(err)
+		}
+
+		// new names of the moved names
+		renamed := make(map[string]string)
+		var moved []*api.Record
+		for i := range list.Records {
+			r := &list.Records[i]
+			name := lintName(r)
+			newName, ok := movedName(name, from, to)
+			if !ok {
+				continue
+			}
+			if strings.ToUpper(r.RecordType) == typeSOA {
+				continue
+			}
+			renamed[name] = newName
+			moved = append(moved, r)
+		}
+		if len(moved) == 0 {
+			throwError(usageError(`No records of "%s" found.`, from))
+		}
+		newNames := make(map[string]bool, len(renamed))
+		for _, newName := range renamed {
+			newNames[newName] = true
+		}
+		for i := range list.Records {
+			name := lintName(&list.Records[i])
+			if _, isMoved := renamed[name]; newNames[name] && !isMoved {
+				throwError(usageError(`"%s" already has records, delete them first.`, name))
+			}
+		}
+
+		var copies []api.Record
+		for _, r := range moved {
+			c := copyRecord(r, renamed[lintName(r)])
+			retargetCname(&c, domain, renamed)
+			fmt.Printf("  + %s\n", formatRecord(&c))
+			copies = append(copies, c)
+		}
+		var retargeted []api.Record
+		changed := moved
+		for i := range list.Records {
+			r := &list.Records[i]
+			if _, isMoved := renamed[lintName(r)]; isMoved {
+				continue
+			}
+			c := *r
+			if retargetCname(&c, domain, renamed) {
+				fmt.Printf("  ~ %s -> %s\n", formatRecord(r), c.Content)
+				retargeted = append(retargeted, c)
+				changed = append(changed, r)
+			}
+		}
+		if !moveKeep {
+			for _, r := range moved {
+				fmt.Printf("  - %s\n", formatRecord(r))
+			}
+		}
+		if moveDryRun || (!moveYes && !confirm(fmt.Sprintf("Move %d record(s) of %s to %s?", len(moved), from, to))) {
+			return
+		}
+		verifyUnchanged(domain, changed)
+
+		for i := range copies {
+			if _, err := dnsProvider.Create(domain, &copies[i]); err != nil {
+				fmt.Fprintf(os.Stderr, "Creating %s failed, deleting the created copies\n", formatRecord(&copies[i]))
+				for _, c := range copies[:i] {
+					if _, delErr := dnsProvider.Delete(domain, c.RecordId); delErr != nil {
+						fmt.Fprintf(os.Stderr, "Error: cannot delete %s: %s\n", formatRecord(&c), delErr)
+					}
+				}
+				throwError(err)
+			}
+			fmt.Printf("Record successfully created: %s\n", formatRecord(&copies[i]))
+		}
+		for i := range retargeted {
+			update := retargeted[i]
+			if _, err := dnsProvider.Update(domain, &update); err != nil {
+				throwError(err)
+			}
+			fmt.Printf("Record successfully changed: %s\n", formatRecord(&retargeted[i]))
+		}
+		if moveKeep {
+			return
+		}
+		for _, r := range moved {
+			if _, err := dnsProvider.Delete(domain, r.RecordId); err != nil {
+				throwError(err)
+			}
+			fmt.Printf("Record successfully deleted: %s\n", formatRecord(r))
+		}
+	},
+}
+
+// movedName returns the new name of the name when the subdomain from is renamed to.
+func movedName(name, from, to string) (string, bool) {
+	if name == from {
+		return to, true
+	}
+	if moveRecursive && strings.HasSuffix(name, "."+from) {
+		return strings.TrimSuffix(name, from) + to, true
+	}
+	return "", false
+}
+
+// copyRecord returns a new record with the data of r under the subdomain.
+func copyRecord(r *api.Record, subdomain string) api.Record {
+	c := api.Record{
+		RecordType: strings.ToUpper(r.RecordType),
+		Subdomain:  subdomain,
+		Content:    r.Content,
+		TTL:        r.TTL,
+		Weight:     r.Weight,
+		Port:       r.Port,
+		Target:     r.Target,
+	}
+	if r.Priority != nil {
+		c.Priority = api.PriorityString(r.Priority)
+	}
+	return c
+}
+
+// retargetCname points the CNAME record at the new name of its target and reports whether it changed.
+func retargetCname(r *api.Record, domain string, renamed map[string]string) bool {
+	if strings.ToUpper(r.RecordType) != typeCNAME {
+		return false
+	}
+	target, ok := relativeName(domain, r.Content)
+	if !ok {
+		return false
+	}
+	newTarget, ok := renamed[target]
+	if !ok {
+		return false
+	}
+	content := newTarget + "." + domain
+	if strings.HasSuffix(r.Content, ".") {
+		content += "."
+	}
+	r.Content = content
+	return true
+}
+
+// relativeName converts a host name to the name within the domain, ok is false for the hosts outside of it.
+func relativeName(domain, host string) (string, bool) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	domain = strings.ToLower(domain)
+	if host == domain {
+		return apexLabel, true
+	}
+	if domain != "" && strings.HasSuffix(host, "."+domain) {
+		return strings.TrimSuffix(host, "."+domain), true
+	}
+	return "", false
+}
+
+func init() {
+	RootCmd.AddCommand(moveCmd)
+
+	moveCmd.Flags().BoolVarP(&moveRecursive, "recursive", "r", false, "move the deeper subdomains as well")
+	moveCmd.Flags().BoolVarP(&moveKeep, "keep", "k", false, "keep the original records (copy instead of move)")
+	moveCmd.Flags().BoolVarP(&moveYes, "yes", "y", false, "do not ask for confirmation")
+	moveCmd.Flags().BoolVarP(&moveDryRun, "dry-run", "n", false, "only show the changes")
+}
@@ -0,0 +1,237 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lexty/yandex-dns-cli-manager/provider"
+	"github.com/spf13/cobra"
+)
+
+var profileAdminToken string
+var profileDomains string
+var profileFormat string
+var profileProps string
+var profileTypes string
+var profileEndpoint string
+var profileTokenRef string
+var profileTokenFile string
+var profileTokenCommand string
+var profileBackend string
+var profileOrgId string
+var profileFilePath string
+
+// profileCmd represents the settings profile command
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage named settings profiles",
+	Long: `Profiles hold the settings of several accounts and domains in one config file.
+The profile is selected with --profile, $` + profileEnvVar + ` or "settings profile use".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var profileAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a profile or change an existing one",
+	Run: func(cmd *cobra.Command, args []string) {
+		name := profileNameArg(args)
+		cfg, err := readConfigFile()
+		if err != nil {
+			throwError(err)
+		}
+		profiles := configProfiles(cfg)
+		profile, ok := profiles[name].(map[string]interface{})
+		if !ok {
+			profile = make(map[string]interface{})
+			profiles[name] = profile
+		}
+
+		if profileAdminToken != "" {
+			profile["admin-token"] = profileAdminToken
+		}
+		if profileDomains != "" {
+			profile[cfgKeyDomains] = parseCommaSep(profileDomains)
+		}
+		if profileFormat != "" {
+			profile["format"] = profileFormat
+		}
+		if profileProps != "" {
+			profile["props"] = profileProps
+		}
+		if profileTypes != "" {
+			profile["types"] = profileTypes
+		}
+		if profileEndpoint != "" {
+			profile[cfgKeyEndpoint] = profileEndpoint
+		}
+		if profileTokenRef != "" {
+			profile[cfgKeyTokenRef] = profileTokenRef
+			delete(profile, "admin-token")
+		}
+		if profileTokenFile != "" {
+			profile[cfgKeyTokenFile] = profileTokenFile
+		}
+		if profileTokenCommand != "" {
+			profile[cfgKeyTokenCommand] = profileTokenCommand
+		}
+		if profileBackend != "" {
+			profile[cfgKeyBackend] = profileBackend
+		}
+		if profileOrgId != "" {
+			profile[cfgKeyOrgId] = profileOrgId
+		}
+		if profileFilePath != "" {
+			profile[cfgKeyFilePath] = profileFilePath
+		}
+
+		if err := writeConfigFile(cfg); err != nil {
+			throwError(err)
+		}
+		if ok {
+			fmt.Printf("Profile \"%s\" successfully changed\n", name)
+		} else {
+			fmt.Printf("Profile \"%s\" successfully added\n", name)
+		}
+	},
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "The list of the profiles",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := readConfigFile()
+		if err != nil {
+			throwError(err)
+		}
+		active := activeProfileName()
+		for _, name := range sortedKeys(configProfiles(cfg)) {
+			mark := " "
+			if name == active {
+				mark = "*"
+			}
+			fmt.Printf("%s %s\n", mark, name)
+		}
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Select the default profile",
+	Run: func(cmd *cobra.Command, args []string) {
+		name := profileNameArg(args)
+		cfg, err := readConfigFile()
+		if err != nil {
+			throwError(err)
+		}
+		if _, ok := configProfiles(cfg)[name]; !ok {
+			throwError(usageError(`Unknown profile "%s".`, name))
+		}
+		cfg[cfgKeyProfile] = name
+		if err := writeConfigFile(cfg); err != nil {
+			throwError(err)
+		}
+		fmt.Printf("Profile \"%s\" is now used by default\n", name)
+	},
+}
+
+var profileRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove the profile",
+	Run: func(cmd *cobra.Command, args []string) {
+		name := profileNameArg(args)
+		cfg, err := readConfigFile()
+		if err != nil {
+			throwError(err)
+		}
+		profiles := configProfiles(cfg)
+		if _, ok := profiles[name]; !ok {
+			throwError(usageError(`Unknown profile "%s".`, name))
+		}
+		delete(profiles, name)
+		if cfg[cfgKeyProfile] == name {
+			delete(cfg, cfgKeyProfile)
+		}
+		if err := writeConfigFile(cfg); err != nil {
+			throwError(err)
+		}
+		fmt.Printf("Profile \"%s\" successfully removed\n", name)
+	},
+}
+
+var profileShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show the profile (default is the active one)",
+	Run: func(cmd *cobra.Command, args []string) {
+		name := activeProfileName()
+		if len(args) > 0 {
+			name = args[0]
+		}
+		if name == "" {
+			throwError(usageError("No profile is selected."))
+		}
+		cfg, err := readConfigFile()
+		if err != nil {
+			throwError(err)
+		}
+		profile, ok := configProfiles(cfg)[name].(map[string]interface{})
+		if !ok {
+			throwError(usageError(`Unknown profile "%s".`, name))
+		}
+		if token, ok := profile["admin-token"].(string); ok {
+			profile["admin-token"] = maskToken(token)
+		}
+		data, err := json.MarshalIndent(profile, "", "    ")
+		if err != nil {
+			throwError(err)
+		}
+		fmt.Printf("Profile \"%s\":\n%s\n", name, data)
+	},
+}
+
+func profileNameArg(args []string) string {
+	if len(args) != 1 || args[0] == "" {
+		throwError(usageError("Profile name is required."))
+	}
+	return args[0]
+}
+
+func init() {
+	settingsCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileAddCmd, profileListCmd, profileUseCmd, profileRemoveCmd, profileShowCmd)
+
+	profileAddCmd.Flags().StringVarP(&profileAdminToken, "admin-token", "a", "", "admin token of the account")
+	profileAddCmd.Flags().StringVarP(&profileDomains, "domains", "D", "", "comma separated domain names, the first one is used by default")
+	profileAddCmd.Flags().StringVarP(&profileFormat, "format", "f", "", "default output format")
+	profileAddCmd.Flags().StringVarP(&profileProps, "props", "p", "", "default output record properties")
+	profileAddCmd.Flags().StringVarP(&profileTypes, "types", "t", "", "default output record types")
+	profileAddCmd.Flags().StringVarP(&profileEndpoint, "endpoint", "e", "", "URL prefix of the DNS API")
+	profileAddCmd.Flags().StringVarP(&profileTokenRef, "token-ref", "r", "", "name of the admin token in the encrypted store")
+	profileAddCmd.Flags().StringVar(&profileTokenFile, "token-file", "", "file containing the admin token")
+	profileAddCmd.Flags().StringVar(&profileTokenCommand, "token-command", "", "shell command printing the admin token")
+	profileAddCmd.Flags().StringVarP(&profileBackend, "backend", "b", "", fmt.Sprintf("DNS provider (%s)", strings.Join(provider.Names(), "|")))
+	profileAddCmd.Flags().StringVarP(&profileOrgId, "org-id", "o", "", "Yandex 360 organization ID")
+	profileAddCmd.Flags().StringVar(&profileFilePath, "file-path", "", "zone file of the file provider")
+}
@@ -0,0 +1,289 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/lexty/yandex-dns-cli-manager/dnsclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var propagationWait bool
+var propagationTimeout time.Duration
+var propagationInterval time.Duration
+var propagationResolvers string
+var propagationNameservers string
+var propagationTCP bool
+
+// nameserver is an authoritative server of the domain
+type nameserver struct {
+	Name string
+	Addr string
+}
+
+// checkCmd represents the check command
+var checkCmd = &cobra.Command{
+	Use:   "check <subdomain> [type]",
+	Short: "Check that the authoritative nameservers serve the records",
+	Long: `Looks up the NS records of the domain, queries every authoritative nameserver directly
+for the name and type (A by default) and compares the answers with the records of the provider.
+Use "@" for the domain itself.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runPropagationCheck(args, propagationWait)
+	},
+}
+
+// waitCmd represents the wait command
+var waitCmd = &cobra.Command{
+	Use:   "wait <subdomain> [type]",
+	Short: "Wait until the authoritative nameservers serve the records",
+	Long:  `Same as "check --wait": blocks until all nameservers agree with the provider or the timeout expires.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runPropagationCheck(args, true)
+	},
+}
+
+func runPropagationCheck(args []string, wait bool) {
+	if len(args) < 1 || len(args) > 2 {
+		throwError(usageError("Subdomain and optional record type are required."))
+	}
+	recordType := typeA
+	if len(args) == 2 {
+		recordType = strings.ToUpper(args[1])
+	}
+	if _, ok := dnsclient.TypeByName(recordType); !ok {
+		throwError(usageError(`Unknown record type "%s".`, recordType))
+	}
+	if wait {
+		checkWaitFlags()
+	}
+	checkRequiredSettings()
+	if !checkPropagation(viper.GetString("domain"), args[0], recordType, wait) {
+		throwError(cliError{exitMismatch, errorCodeMismatch, "the nameservers do not serve the records yet", `wait for the propagation with "wait" or --wait`})
+	}
+}
+
+// checkPropagation compares the answers of the authoritative nameservers with the records of the provider
+// and reports whether all of them agree. With wait it repeats the check until they agree or the timeout expires.
+func checkPropagation(domain, subdomain, recordType string, wait bool) bool {
+	qtype, _ := dnsclient.TypeByName(recordType)
+	name := domain
+	if subdomain != apexLabel && subdomain != "" {
+		name = subdomain + "." + domain
+	}
+
+	servers, err := authoritativeServers(domain)
+	if err != nil {
+		throwError(err)
+	}
+	client := &dnsclient.Client{Timeout: 5 * time.Second, TCP: propagationTCP}
+	deadline := time.Now().Add(propagationTimeout)
+	for {
+		list, err := dnsProvider.List(domain)
+		if err != nil {
+			throwError(err)
+		}
+		expected := expectedAnswers(list.Records, subdomain, recordType)
+
+		fmt.Printf("%s  %s %s, expected: %s\n", time.Now().Format(time.RFC3339), name, recordType, strings.Join(expected, ", "))
+		agree := true
+		for _, ns := range servers {
+			answers, err := client.Lookup(ns.Addr, name, qtype)
+			status := "OK"
+			if err != nil {
+				status = "ERROR"
+				answers = []string{err.Error()}
+			} else {
+				answers = normalizeAnswers(answers, recordType)
+				if !sameStrings(answers, expected) {
+					status = "MISMATCH"
+				}
+			}
+			if status != "OK" {
+				agree = false
+			}
+			fmt.Printf("  %-40s %-8s %s\n", fmt.Sprintf("%s (%s)", ns.Name, ns.Addr), status, strings.Join(answers, ", "))
+		}
+
+		if agree {
+			fmt.Println("All nameservers serve the records")
+			return true
+		}
+		if !wait || time.Now().Add(propagationInterval).After(deadline) {
+			return false
+		}
+		time.Sleep(propagationInterval)
+	}
+}
+
+// authoritativeServers returns the servers given with --nameservers or
+// looks up the NS records of the domain with the resolvers given with --resolver or the system resolver.
+func authoritativeServers(domain string) ([]nameserver, error) {
+	var servers []nameserver
+	if propagationNameservers != "" {
+		for _, addr := range parseCommaSep(propagationNameservers) {
+			servers = append(servers, nameserver{addr, addr})
+		}
+		return servers, nil
+	}
+
+	var names []string
+	if propagationResolvers != "" {
+		var err error
+		if names, err = resolverLookup(domain, dnsclient.TypeNS); err != nil {
+			return nil, err
+		}
+	} else {
+		nss, err := net.LookupNS(domain)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range nss {
+			names = append(names, ns.Host)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no NS records found for %s", domain)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var addrs []string
+		var err error
+		if propagationResolvers != "" {
+			addrs, err = resolverLookup(name, dnsclient.TypeA)
+		} else {
+			addrs, err = net.LookupHost(name)
+		}
+		if err != nil || len(addrs) == 0 {
+			return nil, fmt.Errorf("cannot resolve the nameserver %s: %v", name, err)
+		}
+		servers = append(servers, nameserver{strings.TrimSuffix(name, "."), addrs[0]})
+	}
+	return servers, nil
+}
+
+// resolverLookup asks the resolvers given with --resolver in turn until one of them answers.
+func resolverLookup(name string, qtype uint16) ([]string, error) {
+	client := &dnsclient.Client{Timeout: 5 * time.Second, TCP: propagationTCP, Recursion: true}
+	var lastErr error
+	for _, resolver := range parseCommaSep(propagationResolvers) {
+		answers, err := client.Lookup(resolver, name, qtype)
+		if err == nil {
+			return answers, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// expectedAnswers converts the records of the provider to the form of the DNS answers.
+func expectedAnswers(records []api.Record, subdomain, recordType string) []string {
+	if subdomain == "" {
+		subdomain = apexLabel
+	}
+	var expected []string
+	for _, r := range records {
+		recSubdomain := r.Subdomain
+		if recSubdomain == "" {
+			recSubdomain = apexLabel
+		}
+		if !strings.EqualFold(recSubdomain, subdomain) || strings.ToUpper(r.RecordType) != recordType {
+			continue
+		}
+		priority := api.PriorityString(r.Priority)
+		switch recordType {
+		case typeMX:
+			expected = append(expected, priority+" "+r.Content)
+		case typeSRV:
+			target := r.Target
+			if target == "" {
+				target = r.Content
+			}
+			expected = append(expected, fmt.Sprintf("%s %d %d %s", priority, r.Weight, r.Port, target))
+		default:
+			expected = append(expected, r.Content)
+		}
+	}
+	return normalizeAnswers(expected, recordType)
+}
+
+// normalizeAnswers brings the values to a comparable form: canonical IP addresses,
+// lower case host names without the trailing dot, sorted.
+func normalizeAnswers(values []string, recordType string) []string {
+	normalized := make([]string, len(values))
+	for i, v := range values {
+		switch recordType {
+		case typeA, typeAAAA:
+			if ip := net.ParseIP(v); ip != nil {
+				v = ip.String()
+			}
+		case typeCNAME, typeNS, typeMX, typeSRV:
+			v = strings.TrimSuffix(strings.ToLower(v), ".")
+		}
+		normalized[i] = v
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// checkWaitFlags fails if the flags of waiting for the propagation are invalid.
+func checkWaitFlags() {
+	if propagationInterval <= 0 {
+		throwError(usageError("--interval must be positive."))
+	}
+}
+
+// addPropagationFlags registers the flags controlling the propagation check on the command
+func addPropagationFlags(c *cobra.Command) {
+	c.Flags().DurationVarP(&propagationTimeout, "timeout", "T", 10*time.Minute, "how long to wait for the propagation")
+	c.Flags().DurationVarP(&propagationInterval, "interval", "i", 15*time.Second, "interval between the checks while waiting")
+	c.Flags().StringVarP(&propagationResolvers, "resolver", "r", "", "comma separated resolvers (host[:port]) used to look up the nameservers (default is the system resolver)")
+	c.Flags().StringVarP(&propagationNameservers, "nameservers", "n", "", "comma separated nameservers (host[:port]) to query instead of the NS records of the domain")
+	c.Flags().BoolVar(&propagationTCP, "tcp", false, "query over TCP instead of UDP")
+}
+
+func init() {
+	RootCmd.AddCommand(checkCmd, waitCmd)
+
+	addPropagationFlags(checkCmd)
+	addPropagationFlags(waitCmd)
+	checkCmd.Flags().BoolVarP(&propagationWait, "wait", "w", false, "wait until all nameservers agree")
+}
@@ -0,0 +1,95 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+)
+
+var propagationRecords = []api.Record{
+	{RecordType: "A", Subdomain: "www", Content: "192.0.2.2"},
+	{RecordType: "A", Subdomain: "www", Content: "192.0.2.1"},
+	{RecordType: "a", Subdomain: "WWW", Content: "192.0.2.3"},
+	{RecordType: "A", Subdomain: "mail", Content: "192.0.2.9"},
+	{RecordType: "AAAA", Subdomain: "@", Content: "2001:0db8:0000:0000:0000:0000:0000:0001"},
+	{RecordType: "MX", Subdomain: "", Content: "MX.example.com.", Priority: "10"},
+	{RecordType: "MX", Subdomain: "@", Content: "mx2.example.com", Priority: float64(20)},
+	{RecordType: "SRV", Subdomain: "_sip._tcp", Target: "sip.example.com.", Priority: "10", Weight: 5, Port: 5060},
+	{RecordType: "SRV", Subdomain: "_xmpp._tcp", Content: "xmpp.example.com", Priority: "0", Weight: 0, Port: 5222},
+	{RecordType: "CNAME", Subdomain: "ftp", Content: "Files.Example.NET."},
+	{RecordType: "TXT", Subdomain: "@", Content: "v=spf1 include:_spf.yandex.net ~all"},
+}
+
+func TestExpectedAnswers(t *testing.T) {
+	tests := []struct {
+		subdomain  string
+		recordType string
+		want       []string
+	}{
+		{"www", typeA, []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"}},
+		{"", typeAAAA, []string{"2001:db8::1"}},
+		{"@", typeMX, []string{"10 mx.example.com", "20 mx2.example.com"}},
+		{"_sip._tcp", typeSRV, []string{"10 5 5060 sip.example.com"}},
+		{"_xmpp._tcp", typeSRV, []string{"0 0 5222 xmpp.example.com"}},
+		{"ftp", typeCNAME, []string{"files.example.net"}},
+		{"@", typeTXT, []string{"v=spf1 include:_spf.yandex.net ~all"}},
+		{"missing", typeA, []string{}},
+	}
+	for _, tt := range tests {
+		if got := expectedAnswers(propagationRecords, tt.subdomain, tt.recordType); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expectedAnswers(%q, %s) = %q, want %q", tt.subdomain, tt.recordType, got, tt.want)
+		}
+	}
+}
+
+// TestCompareAnswers checks the answers in the form returned by dnsclient against the records of the provider.
+func TestCompareAnswers(t *testing.T) {
+	tests := []struct {
+		name       string
+		subdomain  string
+		recordType string
+		answers    []string
+		same       bool
+	}{
+		{"A in another order", "www", typeA, []string{"192.0.2.3", "192.0.2.1", "192.0.2.2"}, true},
+		{"A missing an address", "www", typeA, []string{"192.0.2.1", "192.0.2.2"}, false},
+		{"A with an old address", "mail", typeA, []string{"192.0.2.8"}, false},
+		{"AAAA", "@", typeAAAA, []string{"2001:db8::1"}, true},
+		{"MX with the trailing dots", "@", typeMX, []string{"20 mx2.example.com.", "10 mx.example.com."}, true},
+		{"MX with another priority", "@", typeMX, []string{"10 mx.example.com.", "30 mx2.example.com."}, false},
+		{"SRV", "_sip._tcp", typeSRV, []string{"10 5 5060 sip.example.com."}, true},
+		{"SRV with another port", "_sip._tcp", typeSRV, []string{"10 5 5061 sip.example.com."}, false},
+		{"CNAME in another case", "ftp", typeCNAME, []string{"files.example.net."}, true},
+		{"TXT", "@", typeTXT, []string{"v=spf1 include:_spf.yandex.net ~all"}, true},
+		{"TXT in another case", "@", typeTXT, []string{"V=SPF1 include:_spf.yandex.net ~all"}, false},
+		{"no records and no answers", "missing", typeA, nil, true},
+		{"deleted record still served", "missing", typeA, []string{"192.0.2.1"}, false},
+	}
+	for _, tt := range tests {
+		expected := expectedAnswers(propagationRecords, tt.subdomain, tt.recordType)
+		if same := sameStrings(normalizeAnswers(tt.answers, tt.recordType), expected); same != tt.same {
+			t.Errorf("%s: answers %q and records %q match = %v", tt.name, tt.answers, expected, same)
+		}
+	}
+}
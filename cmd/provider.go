@@ -0,0 +1,135 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/lexty/yandex-dns-cli-manager/provider"
+	"github.com/spf13/viper"
+)
+
+const (
+	cfgKeyFilePath = "file-path"
+
+	zoneFileName = ".yandexdns.zone.json"
+)
+
+// dnsProvider is created by checkRequiredSettings
+var dnsProvider provider.Provider
+
+// providerName returns the provider selected by the "backend" setting.
+func providerName() string {
+	if name := viper.GetString(cfgKeyBackend); name != "" {
+		return name
+	}
+	return provider.NamePDD
+}
+
+// providerSetting gives the settings to the provider, the admin token is resolved only when requested.
+func providerSetting(key string) string {
+	switch key {
+	case "admin-token":
+		resolveAdminToken()
+	case cfgKeyFilePath:
+		if viper.GetString(key) == "" {
+			return filepath.Join(filepath.Dir(getDefaultCfgFilepath()), zoneFileName)
+		}
+	}
+	return viper.GetString(key)
+}
+
+// newProvider creates the selected provider or terminates the program.
+func newProvider() provider.Provider {
+	p, err := provider.New(providerName(), providerSetting)
+	switch err {
+	case nil:
+		return p
+	case provider.ErrNoToken:
+		throwError(cliError{exitUsage, errorCodeUsage, "--admin-token is not set", apiErrorHints["no_token"]})
+	case provider.ErrNoOrgId:
+		throwError(cliError{exitUsage, errorCodeUsage, cfgKeyOrgId + " is not set", "the organization ID is shown on admin.yandex.ru"})
+	default:
+		throwError(usageError("%s", err))
+	}
+	return nil
+}
+
+// profileSettings gives the settings of the named profile of the config file, independently of the active
+// profile and the flags, e.g. to read the records of another account. The admin token is resolved when requested.
+func profileSettings(name string) provider.Settings {
+	cfg, err := readConfigFile()
+	if err != nil {
+		throwError(err)
+	}
+	profile, ok := configProfiles(cfg)[name].(map[string]interface{})
+	if !ok {
+		throwError(usageError(`Unknown profile "%s".`, name))
+	}
+	section := make(map[string]interface{}, len(cfg))
+	for key, value := range cfg {
+		section[key] = value
+	}
+	for _, key := range tokenSourceKeys {
+		if _, ok := profile[key]; ok {
+			for _, other := range tokenSourceKeys {
+				delete(section, other)
+			}
+			break
+		}
+	}
+	for key, value := range profile {
+		section[key] = value
+	}
+	get := func(key string) string {
+		if value, ok := section[key]; ok && value != nil {
+			return fmt.Sprint(value)
+		}
+		return ""
+	}
+
+	return func(key string) string {
+		switch key {
+		case "admin-token":
+			var token string
+			var err error
+			if file := get(cfgKeyTokenFile); file != "" {
+				token, err = readTokenFile(file)
+			} else if command := get(cfgKeyTokenCommand); command != "" {
+				token, err = runTokenCommand(command)
+			} else if ref := get(cfgKeyTokenRef); ref != "" {
+				token, err = openTokenStore().Get(ref)
+			} else {
+				return get(key)
+			}
+			if err != nil {
+				throwError(usageError(`Cannot read the admin token of the profile "%s": %s`, name, err))
+			}
+			return token
+		case cfgKeyFilePath:
+			if get(key) == "" {
+				return filepath.Join(filepath.Dir(getDefaultCfgFilepath()), zoneFileName)
+			}
+		}
+		return get(key)
+	}
+}
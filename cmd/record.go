@@ -0,0 +1,150 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/spf13/cobra"
+)
+
+var recordPriority int
+
+// typeFlags are the flags that apply only to some record types
+var typeFlags = map[string][]string{
+	"priority":   {typeMX, typeSRV},
+	"weight":     {typeSRV},
+	"port":       {typeSRV},
+	"target":     {typeSRV},
+	"admin-mail": {typeSOA},
+	"refresh":    {typeSOA},
+	"retry":      {typeSOA},
+	"expire":     {typeSOA},
+	"neg-cache":  {typeSOA},
+}
+
+// requiredTypeFlags are the flags required to add a record of the type
+var requiredTypeFlags = map[string][]string{
+	typeSRV: {"priority", "weight", "port", "target"},
+}
+
+const recordFlagsHelp = `Flags by record type:
+  A, AAAA, CNAME, NS, TXT  --content, --subdomain, --ttl
+  MX                       --content, --subdomain, --ttl, --priority
+  SRV                      --subdomain (e.g. _sip._tcp), --ttl, --priority, --weight, --port, --target
+  SOA                      --admin-mail, --refresh, --retry, --expire, --neg-cache, --ttl`
+
+// addRecordFlags registers the flags of the record fields on the add and edit commands.
+func addRecordFlags(c *cobra.Command) {
+	c.Flags().StringVarP(&rec.Content, "content", "c", "", "content of the DNS record")
+	c.Flags().StringVarP(&rec.Subdomain, "subdomain", "s", "", "Name of the subdomain")
+	c.Flags().IntVarP(&rec.TTL, "ttl", "l", 0, "the lifetime of the DNS record in seconds")
+	c.Flags().IntVarP(&recordPriority, "priority", "p", 0, "priority of the MX or SRV record, lower is preferred")
+	c.Flags().IntVarP(&rec.Weight, "weight", "w", 0, "weight of the SRV-record relative to other SRV-records for the same domain with the same priority")
+	c.Flags().IntVarP(&rec.Port, "port", "P", 0, "TCP or UDP port of the host that is hosting the service (SRV)")
+	c.Flags().StringVarP(&rec.Target, "target", "T", "", "the canonical name of the host providing the service (SRV)")
+	c.Flags().StringVarP(&rec.AdminMail, "admin-mail", "m", "", "email-address of the domain's administrator (SOA)")
+	c.Flags().IntVarP(&rec.Refresh, "refresh", "r", 0, "time between updates (SOA)")
+	c.Flags().IntVarP(&rec.Retry, "retry", "R", 0, "the time between attempts to obtain records (SOA)")
+	c.Flags().IntVarP(&rec.Expire, "expire", "e", 0, "time limit (SOA)")
+	c.Flags().IntVarP(&rec.NegCache, "neg-cache", "n", 0, "caching time (SOA)")
+}
+
+// checkRecordFlags fails when a flag passed to the command does not apply to the record type
+// and sets the priority of the record.
+func checkRecordFlags(c *cobra.Command, recordType string) {
+	recordType = strings.ToUpper(recordType)
+	for _, name := range sortedTypeFlags() {
+		f := c.Flags().Lookup(name)
+		if f == nil || !f.Changed {
+			continue
+		}
+		if types := typeFlags[name]; !containsString(types, recordType) {
+			throwError(usageError("--%s does not apply to %s records (only to %s).", name, recordType, strings.Join(types, ", ")))
+		}
+	}
+	if f := c.Flags().Lookup("priority"); f != nil && (f.Changed || containsString(typeFlags["priority"], recordType) && hasDefaultValue(c, "priority")) {
+		if recordPriority < 0 {
+			throwError(usageError("--priority must not be negative."))
+		}
+		rec.Priority = strconv.Itoa(recordPriority)
+	}
+}
+
+// hasDefaultValue reports whether the record flag got a default from the "commands" section of the config file.
+func hasDefaultValue(c *cobra.Command, name string) bool {
+	f := c.Flags().Lookup(name)
+	return f != nil && f.DefValue != "" && f.DefValue != "0"
+}
+
+// editedFields returns the record fields set with the flags or with their defaults from the config file,
+// the edit clears the fields set to empty values and keeps the others.
+func editedFields(c *cobra.Command) api.FieldSet {
+	var fields api.FieldSet
+	for _, name := range append([]string{"content", "subdomain", "ttl"}, sortedTypeFlags()...) {
+		field, ok := api.FieldByName(strings.Replace(name, "-", "_", -1))
+		if f := c.Flags().Lookup(name); ok && f != nil && (f.Changed || hasDefaultValue(c, name)) {
+			fields |= field
+		}
+	}
+	// the priority is set by checkRecordFlags only if it applies to the record type
+	if rec.Priority == nil {
+		fields &^= api.FieldPriority
+	}
+	return fields
+}
+
+// checkRequiredRecordFlags fails when a flag required for adding a record of the type is missing.
+func checkRequiredRecordFlags(c *cobra.Command, recordType string) {
+	recordType = strings.ToUpper(recordType)
+	var missing []string
+	for _, name := range requiredTypeFlags[recordType] {
+		if f := c.Flags().Lookup(name); f == nil || !f.Changed && !hasDefaultValue(c, name) {
+			missing = append(missing, "--"+name)
+		}
+	}
+	if recordType != typeSRV && recordType != typeSOA && rec.Content == "" {
+		missing = append(missing, "--content")
+	}
+	if len(missing) > 0 {
+		throwError(usageError("%s records require %s.", recordType, strings.Join(missing, ", ")))
+	}
+}
+
+func sortedTypeFlags() []string {
+	names := make([]string, 0, len(typeFlags))
+	for name := range typeFlags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// recordTypes are the record types supported by the commands
+var recordTypes = []string{typeA, typeAAAA, typeCNAME, typeSRV, typeTXT, typeSOA, typeMX, typeNS}
+
+func recordTypesHelp() string {
+	return fmt.Sprintf("type of record (available: %s)", strings.Join(recordTypes, ", "))
+}
@@ -36,26 +36,40 @@ const (
 
 var rec api.Record
 var cfgFile string
+var verbose bool
+var profileName string
 
 // This represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
 	Use:   "yandex-dns-cli-manager",
 	Short: "Yandex DNS CLI manager",
-	Long:  `Yandex DNS CLI manager allows you to change the DNS settings of your domain on pdd.yandex.ru`,
+	Long: `Yandex DNS CLI manager allows you to change the DNS settings of your domain on pdd.yandex.ru
+
+Diagnostics and errors are printed to stderr. With --format json errors are printed as JSON objects.
+
+Exit codes:
+  0  success
+  1  unexpected error
+  2  invalid flags, arguments or settings
+  3  the API rejected the request
+  4  the API could not be reached
+  5  the check failed, e.g. the nameservers do not serve the records yet`,
 	//	Run: func(cmd *cobra.Command, args []string) { },
 }
 
 // Execute adds all child commands to the root command sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	RootCmd.SetArgs(expandAlias(os.Args[1:]))
 	if err := RootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(-1)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
 	}
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
+	bindEnv()
 
 	// Here you will define your flags and configuration settings.
 	// Cobra supports Persistent Flags, which, if defined here,
@@ -64,10 +78,14 @@ func init() {
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/"+cfgFileName+"."+cfgFileType+")")
 
 	RootCmd.PersistentFlags().StringP("admin-token", "a", "", "admin's token")
-	viper.BindPFlag("admin-token", RootCmd.Flags().Lookup("admin-token"))
+	viper.BindPFlag("admin-token", RootCmd.PersistentFlags().Lookup("admin-token"))
 
 	RootCmd.PersistentFlags().StringP("domain", "d", "", "domain name")
-	viper.BindPFlag("domain", RootCmd.Flags().Lookup("domain"))
+	viper.BindPFlag("domain", RootCmd.PersistentFlags().Lookup("domain"))
+
+	RootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "settings profile (default is $"+profileEnvVar+" or the profile selected in the config file)")
+
+	RootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "print requests to stderr")
 
 	//	RootCmd.PersistentFlags().StringVarP(&Token, "token", "t", "", "your token")
 	//	RootCmd.PersistentFlags().StringVarP(&Domain, "domain", "d", "", "domain name")
@@ -76,8 +94,21 @@ func init() {
 	//	RootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
+// checkRequiredSettings creates the DNS provider and
+// terminates the program if the domain or the settings of the provider are not set.
+func checkRequiredSettings() {
+	if viper.GetString("domain") == "" {
+		throwError(cliError{exitUsage, errorCodeUsage, "--domain is not set", apiErrorHints["no_domain"]})
+	}
+	dnsProvider = withJournal(newProvider())
+}
+
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
+	if verbose {
+		api.DebugOutput = os.Stderr
+	}
+
 	if cfgFile != "" { // enable ability to specify config file via flag
 		viper.SetConfigFile(cfgFile)
 	}
@@ -88,6 +119,8 @@ func initConfig() {
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
+
+	applyProfile()
 }
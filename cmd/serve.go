@@ -0,0 +1,484 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/lexty/yandex-dns-cli-manager/provider"
+	"github.com/spf13/cobra"
+)
+
+const (
+	serveDefaultListen = "127.0.0.1:8053"
+	serveMaxBodySize   = 1 << 20
+	serveRecordsPath   = "/v1/domains/"
+	serveOpenAPIPath   = "/openapi.json"
+)
+
+var serveListen string
+var serveTLSCert string
+var serveTLSKey string
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the DNS operations over an HTTP REST API",
+	Long: `Serve the DNS operations over an HTTP REST API, e.g. for the scripts and services which must not know the admin token.
+
+The clients authenticate with the API keys created by "serve key add", passed in the "Authorization: Bearer <key>"
+or the "X-API-Key" header. A key is allowed to run only its operations (list, add, edit, delete) on its domains.
+The admin token never leaves the server. The OpenAPI description is served on ` + serveOpenAPIPath + `.
+
+Endpoints:
+  GET    /v1/domains/{domain}/records       list the records
+  POST   /v1/domains/{domain}/records       add a record
+  GET    /v1/domains/{domain}/records/{id}  get the record
+  PATCH  /v1/domains/{domain}/records/{id}  change the fields of the record (PUT is the same)
+  DELETE /v1/domains/{domain}/records/{id}  delete the record
+
+The changes honour the "If-Match" header with the fingerprint of the record (returned in "ETag")
+and are recorded in the journal with the user "api:<key name>".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if (serveTLSCert == "") != (serveTLSKey == "") {
+			throwError(usageError("--tls-cert and --tls-key must be passed together."))
+		}
+		keys := loadAPIKeys()
+		if len(keys) == 0 {
+			throwError(cliError{exitUsage, errorCodeUsage, "no API keys", `create a key with "serve key add <name> --domains <domain>"`})
+		}
+		s := &apiServer{provider: newProvider(), keys: keys}
+		server := &http.Server{
+			Addr:              serveListen,
+			Handler:           s,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      2 * time.Minute,
+			IdleTimeout:       2 * time.Minute,
+		}
+		if serveTLSCert == "" && !isLoopbackListen(serveListen) {
+			fmt.Fprintf(os.Stderr, "Warning: the API keys are sent in clear text to %s, pass --tls-cert and --tls-key\n", serveListen)
+		}
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			sig := <-signals
+			fmt.Fprintf(os.Stderr, "%s  %s received, shutting down\n", time.Now().Format(time.RFC3339), sig)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			server.Shutdown(ctx)
+		}()
+
+		fmt.Fprintf(os.Stderr, "%s  Listening on %s with %d API key(s)\n", time.Now().Format(time.RFC3339), serveListen, len(keys))
+		var err error
+		if serveTLSCert != "" {
+			err = server.ListenAndServeTLS(serveTLSCert, serveTLSKey)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			throwError(err)
+		}
+	},
+}
+
+// isLoopbackListen reports whether the listen address accepts only the local connections.
+func isLoopbackListen(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// apiServer serves the records of the domains allowed to the API keys
+type apiServer struct {
+	provider provider.Provider
+	keys     []*apiKey
+	// changes are made one at a time so the journal entries are chained in order
+	mu sync.Mutex
+}
+
+// apiRecord is the record with its fingerprint as returned by the server
+type apiRecord struct {
+	api.Record
+	Fingerprint string `json:"fingerprint"`
+}
+
+// serveError is the JSON body of the error responses
+type serveError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// statusWriter remembers the status of the response for the request log
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (s *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	keyName := s.handle(sw, r)
+	if keyName == "" {
+		keyName = "-"
+	}
+	fmt.Fprintf(os.Stderr, "%s  %s \"%s %s\" %d %s key=%s\n", start.Format(time.RFC3339), r.RemoteAddr, r.Method, r.URL.Path,
+		sw.status, time.Since(start).Round(time.Millisecond), keyName)
+}
+
+// handle routes the request and returns the name of the API key used.
+func (s *apiServer) handle(w http.ResponseWriter, r *http.Request) string {
+	if r.URL.Path == serveOpenAPIPath {
+		if r.Method != http.MethodGet {
+			writeServeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+			return ""
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, openAPISpec)
+		return ""
+	}
+
+	domain, id, ok := parseRecordsPath(r.URL.Path)
+	if !ok {
+		writeServeError(w, http.StatusNotFound, "not_found", "unknown path "+r.URL.Path)
+		return ""
+	}
+	key := s.authenticate(r)
+	if key == nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeServeError(w, http.StatusUnauthorized, "unauthorized", "missing or invalid API key")
+		return ""
+	}
+
+	var operation string
+	switch {
+	case r.Method == http.MethodGet:
+		operation = opList
+	case r.Method == http.MethodPost && id == 0:
+		operation = opAdd
+	case (r.Method == http.MethodPut || r.Method == http.MethodPatch) && id != 0:
+		operation = opEdit
+	case r.Method == http.MethodDelete && id != 0:
+		operation = opDelete
+	default:
+		writeServeError(w, http.StatusMethodNotAllowed, "method_not_allowed", r.Method+" is not allowed on "+r.URL.Path)
+		return key.Name
+	}
+	if !key.allows(domain, operation) {
+		writeServeError(w, http.StatusForbidden, "forbidden", fmt.Sprintf(`the key is not allowed to %s the records of %s`, operation, domain))
+		return key.Name
+	}
+
+	p := s.providerFor(key)
+	switch operation {
+	case opList:
+		if id == 0 {
+			s.listRecords(w, p, domain)
+		} else {
+			s.getRecord(w, p, domain, id)
+		}
+	default:
+		r.Body = http.MaxBytesReader(w, r.Body, serveMaxBodySize)
+		s.mu.Lock()
+		unlock, err := tryLockDomain(domain)
+		if err != nil {
+			s.mu.Unlock()
+			writeServeError(w, http.StatusInternalServerError, errorCodeError, "cannot lock the domain: "+err.Error())
+			return key.Name
+		}
+		s.changeRecord(w, r, p, operation, domain, id)
+		unlock()
+		s.mu.Unlock()
+	}
+	return key.Name
+}
+
+// parseRecordsPath splits /v1/domains/{domain}/records[/{id}], the ID is 0 for the collection.
+func parseRecordsPath(path string) (string, int, bool) {
+	if !strings.HasPrefix(path, serveRecordsPath) {
+		return "", 0, false
+	}
+	parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(path, serveRecordsPath), "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] != "records" {
+		return "", 0, false
+	}
+	domain := strings.ToLower(strings.TrimSuffix(parts[0], "."))
+	if len(parts) == 2 {
+		return domain, 0, true
+	}
+	id, err := strconv.Atoi(parts[2])
+	if err != nil || id <= 0 {
+		return "", 0, false
+	}
+	return domain, id, true
+}
+
+// authenticate returns the API key of the request, nil if it has none or an unknown one.
+func (s *apiServer) authenticate(r *http.Request) *apiKey {
+	secret := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); secret == "" && len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		secret = strings.TrimSpace(auth[7:])
+	}
+	if secret == "" {
+		return nil
+	}
+	return findAPIKey(s.keys, secret)
+}
+
+// providerFor records the changes made with the key in the journal under the name of the key.
+func (s *apiServer) providerFor(key *apiKey) provider.Provider {
+	p := withJournal(s.provider)
+	if jp, ok := p.(*journalProvider); ok {
+		jp.user = "api:" + key.Name
+	}
+	return p
+}
+
+func (s *apiServer) listRecords(w http.ResponseWriter, p provider.Provider, domain string) {
+	list, err := p.List(domain)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+	records := make([]apiRecord, len(list.Records))
+	for i := range list.Records {
+		records[i] = newAPIRecord(&list.Records[i])
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"domain": domain, "records": records})
+}
+
+func (s *apiServer) getRecord(w http.ResponseWriter, p provider.Provider, domain string, id int) {
+	current, ok := findServedRecord(w, p, domain, id)
+	if !ok {
+		return
+	}
+	writeRecord(w, http.StatusOK, current)
+}
+
+// changeRecord adds, edits or deletes the record, the If-Match header is checked against the current record.
+func (s *apiServer) changeRecord(w http.ResponseWriter, r *http.Request, p provider.Provider, operation, domain string, id int) {
+	var current *api.Record
+	if id != 0 {
+		var ok bool
+		if current, ok = findServedRecord(w, p, domain, id); !ok {
+			return
+		}
+		if match := r.Header.Get("If-Match"); match != "" && match != "*" && !strings.EqualFold(strings.Trim(match, `"`), recordFingerprint(current)) {
+			writeServeError(w, http.StatusPreconditionFailed, errorCodeMismatch, fmt.Sprintf("record #%d was changed, its fingerprint is %s", id, recordFingerprint(current)))
+			return
+		}
+	}
+
+	if operation == opDelete {
+		if _, err := p.Delete(domain, id); err != nil {
+			writeProviderError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, errorCodeUsage, "cannot read the body: "+err.Error())
+		return
+	}
+	var body api.Record
+	if err := json.Unmarshal(data, &body); err != nil {
+		writeServeError(w, http.StatusBadRequest, errorCodeUsage, "invalid JSON body: "+err.Error())
+		return
+	}
+	if err := checkServedRecord(&body, current, p.Capabilities()); err != nil {
+		writeServeError(w, http.StatusBadRequest, errorCodeUsage, err.Error())
+		return
+	}
+	if current != nil {
+		// the fields of the body are set on the current record, the backends replacing
+		// the whole record keep its type, name and the other fields
+		body = *current
+		json.Unmarshal(data, &body)
+		body.RecordId = id
+		body.RecordType = current.RecordType
+		// the fields present in the body are set even when empty, e.g. "ttl": 0 resets the TTL
+		var keys map[string]json.RawMessage
+		json.Unmarshal(data, &keys)
+		for key := range keys {
+			if field, ok := api.FieldByName(key); ok {
+				body.Fields |= field
+			}
+		}
+	}
+
+	var resp api.Response
+	status := http.StatusOK
+	if operation == opAdd {
+		resp, err = p.Create(domain, &body)
+		status = http.StatusCreated
+	} else {
+		resp, err = p.Update(domain, &body)
+	}
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+	if resp.Record.RecordId != 0 {
+		body = resp.Record
+	}
+	if operation == opAdd {
+		w.Header().Set("Location", serveRecordsPath+url.PathEscape(domain)+"/records/"+strconv.Itoa(body.RecordId))
+	}
+	writeRecord(w, status, &body)
+}
+
+// checkServedRecord validates the record of the request body like the flags of add and edit,
+// current is the edited record, nil for a new one.
+func checkServedRecord(r *api.Record, current *api.Record, caps provider.Capabilities) error {
+	recordType := strings.ToUpper(r.RecordType)
+	if current != nil {
+		if recordType != "" && recordType != strings.ToUpper(current.RecordType) {
+			return fmt.Errorf("the type of record #%d cannot be changed", current.RecordId)
+		}
+		recordType = strings.ToUpper(current.RecordType)
+	} else {
+		if recordType == "" {
+			return fmt.Errorf(`"type" is required (%s)`, strings.Join(caps.RecordTypes, ", "))
+		}
+		r.RecordType = recordType
+	}
+	if !caps.SupportsType(recordType) {
+		return fmt.Errorf("%s records are not supported (%s)", recordType, strings.Join(caps.RecordTypes, ", "))
+	}
+
+	set := map[string]bool{
+		"priority":   api.PriorityString(r.Priority) != "",
+		"weight":     r.Weight != 0,
+		"port":       r.Port != 0,
+		"target":     r.Target != "",
+		"admin-mail": r.AdminMail != "",
+		"refresh":    r.Refresh != 0,
+		"retry":      r.Retry != 0,
+		"expire":     r.Expire != 0,
+		"neg-cache":  r.NegCache != 0,
+	}
+	for _, name := range sortedTypeFlags() {
+		if set[name] && !containsString(typeFlags[name], recordType) {
+			return fmt.Errorf(`"%s" does not apply to %s records`, strings.Replace(name, "-", "_", -1), recordType)
+		}
+	}
+	if current != nil {
+		return nil
+	}
+	var missing []string
+	for _, name := range requiredTypeFlags[recordType] {
+		if !set[name] {
+			missing = append(missing, `"`+strings.Replace(name, "-", "_", -1)+`"`)
+		}
+	}
+	if recordType != typeSRV && recordType != typeSOA && r.Content == "" {
+		missing = append(missing, `"content"`)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s records require %s", recordType, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// findServedRecord returns the record, the error response is written if it cannot be found.
+func findServedRecord(w http.ResponseWriter, p provider.Provider, domain string, id int) (*api.Record, bool) {
+	list, err := p.List(domain)
+	if err != nil {
+		writeProviderError(w, err)
+		return nil, false
+	}
+	for i := range list.Records {
+		if list.Records[i].RecordId == id {
+			return &list.Records[i], true
+		}
+	}
+	writeServeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("no record with ID %d in %s", id, domain))
+	return nil, false
+}
+
+func newAPIRecord(r *api.Record) apiRecord {
+	return apiRecord{Record: *r, Fingerprint: recordFingerprint(r)}
+}
+
+func writeRecord(w http.ResponseWriter, status int, r *api.Record) {
+	w.Header().Set("ETag", `"`+recordFingerprint(r)+`"`)
+	writeJSON(w, status, newAPIRecord(r))
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeServeError(w http.ResponseWriter, status int, code, msg string) {
+	writeJSON(w, status, serveError{code, msg})
+}
+
+// writeProviderError maps the errors of the provider: the rejected requests are the client's fault,
+// the unreachable API is the gateway's.
+func writeProviderError(w http.ResponseWriter, err error) {
+	switch e := err.(type) {
+	case api.ApiError:
+		writeServeError(w, http.StatusBadRequest, errorCodeAPI, e.Error())
+	case *url.Error, net.Error:
+		writeServeError(w, http.StatusBadGateway, errorCodeNetwork, "the DNS API cannot be reached")
+	default:
+		writeServeError(w, http.StatusBadGateway, errorCodeError, e.Error())
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVarP(&serveListen, "listen", "l", serveDefaultListen, "address to listen on")
+	serveCmd.Flags().StringVar(&serveTLSCert, "tls-cert", "", "certificate file to serve HTTPS")
+	serveCmd.Flags().StringVar(&serveTLSKey, "tls-key", "", "private key file of the certificate")
+}
@@ -0,0 +1,207 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	cfgKeyAPIKeys = "api-keys"
+
+	apiKeyPrefix = "ydns_"
+
+	opList   = "list"
+	opAdd    = "add"
+	opEdit   = "edit"
+	opDelete = "delete"
+)
+
+var apiOperations = []string{opList, opAdd, opEdit, opDelete}
+
+var apiKeyDomains string
+var apiKeyOperations string
+
+// apiKey is a key of the HTTP server, only the hash of the key is kept in the config file
+type apiKey struct {
+	Name       string
+	Hash       string
+	Domains    []string
+	Operations []string
+}
+
+// allows reports whether the key may run the operation on the domain, "*" allows any domain.
+func (k *apiKey) allows(domain, operation string) bool {
+	if !containsString(k.Operations, operation) {
+		return false
+	}
+	for _, d := range k.Domains {
+		if d == "*" || strings.EqualFold(d, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+var serveKeyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Manage the API keys of the HTTP server",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var serveKeyAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Create an API key allowed to run the operations on the domains",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			throwError(usageError("The key name is required."))
+		}
+		domains, operations := splitList(apiKeyDomains), splitList(apiKeyOperations)
+		if len(domains) == 0 {
+			throwError(usageError("--domains is required."))
+		}
+		for _, op := range operations {
+			if !containsString(apiOperations, op) {
+				throwError(usageError(`Unknown operation "%s" (available: %s).`, op, strings.Join(apiOperations, ", ")))
+			}
+		}
+
+		secret := make([]byte, 24)
+		if _, err := rand.Read(secret); err != nil {
+			throwError(err)
+		}
+		key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+		cfg, err := readConfigFile()
+		if err != nil {
+			throwError(err)
+		}
+		keys, ok := cfg[cfgKeyAPIKeys].(map[string]interface{})
+		if !ok {
+			keys = make(map[string]interface{})
+			cfg[cfgKeyAPIKeys] = keys
+		}
+		keys[args[0]] = map[string]interface{}{
+			"key_sha256": hashAPIKey(key),
+			"domains":    domains,
+			"operations": operations,
+		}
+		if err := writeConfigFile(cfg); err != nil {
+			throwError(err)
+		}
+		fmt.Printf("API key \"%s\" saved in \"%s\", it is shown only once:\n%s\n", args[0], configFilepath(), key)
+	},
+}
+
+var serveKeyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the API keys",
+	Run: func(cmd *cobra.Command, args []string) {
+		keys := loadAPIKeys()
+		rows := make([][]string, len(keys))
+		for i, k := range keys {
+			rows[i] = []string{k.Name, strings.Join(k.Domains, ","), strings.Join(k.Operations, ",")}
+		}
+		printPlainTable([]string{"Name", "Domains", "Operations"}, rows)
+	},
+}
+
+var serveKeyRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove the API key",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			throwError(usageError("The key name is required."))
+		}
+		cfg, err := readConfigFile()
+		if err != nil {
+			throwError(err)
+		}
+		keys, _ := cfg[cfgKeyAPIKeys].(map[string]interface{})
+		if _, ok := keys[args[0]]; !ok {
+			throwError(usageError(`Unknown API key "%s".`, args[0]))
+		}
+		delete(keys, args[0])
+		if err := writeConfigFile(cfg); err != nil {
+			throwError(err)
+		}
+		fmt.Printf("API key \"%s\" removed\n", args[0])
+	},
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadAPIKeys reads the API keys from the config file.
+func loadAPIKeys() []*apiKey {
+	cfg, err := readConfigFile()
+	if err != nil {
+		throwError(err)
+	}
+	section, _ := cfg[cfgKeyAPIKeys].(map[string]interface{})
+	var keys []*apiKey
+	for _, name := range sortedKeys(section) {
+		entry, ok := section[name].(map[string]interface{})
+		if !ok {
+			throwError(usageError(`Invalid API key "%s" in the config file.`, name))
+		}
+		hash, _ := entry["key_sha256"].(string)
+		keys = append(keys, &apiKey{
+			Name:       name,
+			Hash:       hash,
+			Domains:    toStringSlice(entry["domains"]),
+			Operations: toStringSlice(entry["operations"]),
+		})
+	}
+	return keys
+}
+
+// findAPIKey returns the key matching the secret, nil if none does.
+func findAPIKey(keys []*apiKey, secret string) *apiKey {
+	hash := []byte(hashAPIKey(secret))
+	var found *apiKey
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare(hash, []byte(k.Hash)) == 1 {
+			found = k
+		}
+	}
+	return found
+}
+
+func init() {
+	serveCmd.AddCommand(serveKeyCmd)
+	serveKeyCmd.AddCommand(serveKeyAddCmd, serveKeyListCmd, serveKeyRemoveCmd)
+
+	serveKeyAddCmd.Flags().StringVarP(&apiKeyDomains, "domains", "D", "", `comma separated domains the key may manage ("*" for any)`)
+	serveKeyAddCmd.Flags().StringVarP(&apiKeyOperations, "operations", "o", opList, fmt.Sprintf("comma separated operations the key may run (%s)", strings.Join(apiOperations, ", ")))
+}
@@ -0,0 +1,172 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+// openAPISpec describes the REST API of the serve command
+const openAPISpec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "yandexdns REST API",
+    "description": "DNS records of the domains allowed to the API key.",
+    "version": "1"
+  },
+  "security": [{"bearer": []}, {"apiKey": []}],
+  "paths": {
+    "/v1/domains/{domain}/records": {
+      "parameters": [{"$ref": "#/components/parameters/domain"}],
+      "get": {
+        "summary": "List the records",
+        "operationId": "listRecords",
+        "responses": {
+          "200": {
+            "description": "The records of the domain",
+            "content": {"application/json": {"schema": {
+              "type": "object",
+              "properties": {
+                "domain": {"type": "string"},
+                "records": {"type": "array", "items": {"$ref": "#/components/schemas/Record"}}
+              }
+            }}}
+          },
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "summary": "Add a record",
+        "operationId": "addRecord",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/RecordInput"}}}},
+        "responses": {
+          "201": {"$ref": "#/components/responses/Record"},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/v1/domains/{domain}/records/{id}": {
+      "parameters": [{"$ref": "#/components/parameters/domain"}, {"$ref": "#/components/parameters/id"}],
+      "get": {
+        "summary": "Get the record",
+        "operationId": "getRecord",
+        "responses": {
+          "200": {"$ref": "#/components/responses/Record"},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "patch": {
+        "summary": "Change the fields of the record, the type cannot be changed",
+        "operationId": "editRecord",
+        "parameters": [{"$ref": "#/components/parameters/ifMatch"}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/RecordInput"}}}},
+        "responses": {
+          "200": {"$ref": "#/components/responses/Record"},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "put": {
+        "summary": "Same as PATCH",
+        "operationId": "putRecord",
+        "parameters": [{"$ref": "#/components/parameters/ifMatch"}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/RecordInput"}}}},
+        "responses": {
+          "200": {"$ref": "#/components/responses/Record"},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "summary": "Delete the record",
+        "operationId": "deleteRecord",
+        "parameters": [{"$ref": "#/components/parameters/ifMatch"}],
+        "responses": {
+          "204": {"description": "The record is deleted"},
+          "default": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "bearer": {"type": "http", "scheme": "bearer"},
+      "apiKey": {"type": "apiKey", "in": "header", "name": "X-API-Key"}
+    },
+    "parameters": {
+      "domain": {"name": "domain", "in": "path", "required": true, "schema": {"type": "string"}},
+      "id": {"name": "id", "in": "path", "required": true, "schema": {"type": "integer"}},
+      "ifMatch": {
+        "name": "If-Match", "in": "header", "required": false,
+        "description": "Fingerprint of the record (the ETag), the change is refused with 412 if the record was changed meanwhile",
+        "schema": {"type": "string"}
+      }
+    },
+    "responses": {
+      "Record": {
+        "description": "The record",
+        "headers": {"ETag": {"description": "Fingerprint of the record", "schema": {"type": "string"}}},
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Record"}}}
+      },
+      "Error": {
+        "description": "400 invalid request or rejected by the DNS API, 401 missing API key, 403 operation or domain not allowed, 404 no such record, 412 If-Match mismatch, 502 DNS API unreachable",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}
+      }
+    },
+    "schemas": {
+      "RecordInput": {
+        "type": "object",
+        "properties": {
+          "type": {"type": "string", "enum": ["A", "AAAA", "CNAME", "MX", "NS", "SOA", "SRV", "TXT"]},
+          "subdomain": {"type": "string"},
+          "content": {"type": "string"},
+          "ttl": {"type": "integer"},
+          "priority": {"type": "integer", "description": "MX and SRV"},
+          "weight": {"type": "integer", "description": "SRV"},
+          "port": {"type": "integer", "description": "SRV"},
+          "target": {"type": "string", "description": "SRV"},
+          "admin_mail": {"type": "string", "description": "SOA"},
+          "refresh": {"type": "integer", "description": "SOA"},
+          "retry": {"type": "integer", "description": "SOA"},
+          "expire": {"type": "integer", "description": "SOA"},
+          "neg_cache": {"type": "integer", "description": "SOA"}
+        }
+      },
+      "Record": {
+        "allOf": [
+          {"$ref": "#/components/schemas/RecordInput"},
+          {
+            "type": "object",
+            "properties": {
+              "record_id": {"type": "integer"},
+              "domain": {"type": "string"},
+              "fqdn": {"type": "string"},
+              "minttl": {"type": "integer"},
+              "fingerprint": {"type": "string"}
+            }
+          }
+        ]
+      },
+      "Error": {
+        "type": "object",
+        "properties": {
+          "error": {"type": "string"},
+          "message": {"type": "string"}
+        }
+      }
+    }
+  }
+}
+`
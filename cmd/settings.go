@@ -21,10 +21,7 @@
 package cmd
 
 import (
-	"io/ioutil"
-	"log"
 	"os/user"
-	"strings"
 
 	"os"
 
@@ -37,12 +34,17 @@ import (
 var newAdminToken string
 var newDomain string
 var newProps string
+var explain bool
 
 // settingsCmd represents the settings command
 var settingsCmd = &cobra.Command{
 	Use:   "settings",
 	Short: "Show or change settings",
 	Run: func(cmd *cobra.Command, args []string) {
+		if explain {
+			explainSettings()
+			return
+		}
 		if "" == newAdminToken && "" == newDomain && "" == newProps {
 			printSettings()
 		} else {
@@ -57,49 +59,58 @@ func printSettings() {
 		props = propsDefault
 	}
 
+	var token string
+	switch {
+	case isOverridden("admin-token"):
+		token = maskToken(viper.GetString("admin-token"))
+	case viper.GetString(cfgKeyTokenFile) != "":
+		token = "file:" + viper.GetString(cfgKeyTokenFile)
+	case viper.GetString(cfgKeyTokenCommand) != "":
+		token = "command:" + viper.GetString(cfgKeyTokenCommand)
+	case viper.GetString(cfgKeyTokenRef) != "":
+		token = "stored:" + viper.GetString(cfgKeyTokenRef)
+	case viper.GetString("admin-token") != "":
+		token = maskToken(viper.GetString("admin-token"))
+	}
+
 	fmt.Printf(`Settings:
+	profile     %s
 	admin-token %s
 	domain      %s
 	props       %s
-`, viper.GetString("admin-token"), viper.GetString("domain"), props)
+`, activeProfileName(), token, viper.GetString("domain"), props)
 }
 
+// saveSettings stores the changed settings in the config file,
+// or in the profile if it was selected with --profile.
 func saveSettings() {
-	if "" == newAdminToken && viper.IsSet("admin-token") {
-		newAdminToken = viper.GetString("admin-token")
-	}
-	if "" == newDomain && viper.IsSet("domain") {
-		newDomain = viper.GetString("domain")
+	cfg, err := readConfigFile()
+	if err != nil {
+		throwError(err)
 	}
 
-	var lines []string
+	section := selectedConfigSection(cfg)
+
 	if newAdminToken != "" {
-		lines = append(lines, `    "admin-token": "`+newAdminToken+`"`)
+		section["admin-token"] = newAdminToken
 	}
 	if newDomain != "" {
-		lines = append(lines, `    "domain": "`+newDomain+`"`)
+		section["domain"] = newDomain
 	}
 	if newProps != "" {
-		lines = append(lines, `    "props": "`+newProps+`"`)
+		section["props"] = newProps
 	}
 
-	fmt.Println(newAdminToken)
-
-	cfgContent := "{\n" + strings.Join(lines, ",\n") + "\n}"
-
-	if cfgFile == "" {
-		cfgFile = getDefaultCfgFilepath()
-	}
-	if err := ioutil.WriteFile(cfgFile, []byte(cfgContent), 0600); err != nil {
-		log.Fatal(err)
+	if err := writeConfigFile(cfg); err != nil {
+		throwError(err)
 	}
-	fmt.Printf("Settings successfully changed in \"%s\"\n", cfgFile)
+	fmt.Printf("Settings successfully changed in \"%s\"\n", configFilepath())
 }
 
 func getDefaultCfgFilepath() string {
 	usr, err := user.Current()
 	if err != nil {
-		log.Fatal(err)
+		throwError(err)
 	}
 
 	return usr.HomeDir + string(os.PathSeparator) + cfgFileName + "." + cfgFileType
@@ -110,4 +121,5 @@ func init() {
 	settingsCmd.Flags().StringVarP(&newAdminToken, "admin-token", "a", "", "set your admin token")
 	settingsCmd.Flags().StringVarP(&newDomain, "domain", "d", "", "set domain name")
 	settingsCmd.Flags().StringVarP(&newProps, "props", "p", "", "set default output record properties")
+	settingsCmd.Flags().BoolVarP(&explain, "explain", "e", false, "show the effective settings and where they came from")
 }
@@ -0,0 +1,198 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/lexty/yandex-dns-cli-manager/secrets"
+	"github.com/spf13/viper"
+)
+
+const (
+	cfgKeyTokenFile    = "admin-token-file"
+	cfgKeyTokenCommand = "admin-token-command"
+
+	envPrefix = "YANDEX_DNS_"
+
+	sourceDefault = "default"
+)
+
+// settings which can be set with the YANDEX_DNS_* environment variables
+var envKeys = []string{"admin-token", cfgKeyTokenFile, cfgKeyTokenCommand, "domain", "format", "props", "types", cfgKeyEndpoint, cfgKeyBackend, cfgKeyOrgId, cfgKeyFilePath, cfgKeyJournal}
+
+// settings shown by "settings --explain"
+var explainKeys = []string{"admin-token", cfgKeyTokenFile, cfgKeyTokenCommand, cfgKeyTokenRef, "domain", "format", "props", "types", cfgKeyBackend, cfgKeyOrgId, cfgKeyEndpoint, cfgKeyFilePath}
+
+// the settings defining the admin token, a profile setting one of them overrides all of them
+var tokenSourceKeys = []string{"admin-token", cfgKeyTokenFile, cfgKeyTokenCommand, cfgKeyTokenRef}
+
+// replaceTokenSource sets the token setting of the config section and removes the other token settings,
+// which would take priority over it. The removed settings are reported.
+func replaceTokenSource(section map[string]interface{}, key string, value interface{}) {
+	for _, other := range tokenSourceKeys {
+		if _, ok := section[other]; ok && other != key {
+			delete(section, other)
+			if other != "admin-token" && other != cfgKeyTokenRef {
+				fmt.Printf("Removed the setting \"%s\" which took priority over the new token\n", other)
+			}
+		}
+	}
+	section[key] = value
+}
+
+// settingSources remembers where the settings set by the program came from
+var settingSources = make(map[string]string)
+
+var adminTokenResolved bool
+
+// envVarName returns the name of the environment variable of the setting, e.g. YANDEX_DNS_ADMIN_TOKEN.
+func envVarName(key string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(key, "-", "_", -1))
+}
+
+func bindEnv() {
+	for _, key := range envKeys {
+		viper.BindEnv(key, envVarName(key))
+	}
+}
+
+// isOverridden reports whether the setting is passed with a flag or an environment variable.
+func isOverridden(key string) bool {
+	return isFlagChanged(key) || os.Getenv(envVarName(key)) != ""
+}
+
+// settingSource describes where the effective value of the setting came from.
+func settingSource(key string) string {
+	if isFlagChanged(key) {
+		return "flag --" + key
+	}
+	if os.Getenv(envVarName(key)) != "" {
+		return "environment $" + envVarName(key)
+	}
+	if source, ok := settingSources[key]; ok {
+		return source
+	}
+	if viper.IsSet(key) {
+		return fmt.Sprintf(`config file "%s"`, configFilepath())
+	}
+	return sourceDefault
+}
+
+// resolveAdminToken finds the admin token in the order:
+// flag, environment, token file, token command, token store, profile, config file.
+func resolveAdminToken() {
+	if err := loadAdminToken(); err != nil {
+		throwError(err)
+	}
+}
+
+// loadAdminToken is resolveAdminToken returning the error, e.g. to report it as a failed check.
+func loadAdminToken() error {
+	if adminTokenResolved {
+		return nil
+	}
+	adminTokenResolved = true
+	if isOverridden("admin-token") {
+		return nil
+	}
+
+	var token string
+	var err error
+	if file := viper.GetString(cfgKeyTokenFile); file != "" {
+		token, err = readTokenFile(file)
+		settingSources["admin-token"] = fmt.Sprintf(`file "%s" (%s)`, file, settingSource(cfgKeyTokenFile))
+	} else if command := viper.GetString(cfgKeyTokenCommand); command != "" {
+		token, err = runTokenCommand(command)
+		settingSources["admin-token"] = fmt.Sprintf("command `%s` (%s)", command, settingSource(cfgKeyTokenCommand))
+	} else if name := viper.GetString(cfgKeyTokenRef); name != "" {
+		var store *secrets.Store
+		if store, err = unlockTokenStore(); err == nil {
+			token, err = store.Get(name)
+		}
+		settingSources["admin-token"] = fmt.Sprintf(`token store "%s" (%s)`, name, settingSource(cfgKeyTokenRef))
+	} else {
+		return nil
+	}
+	if err != nil {
+		return usageError("Cannot read the admin token from %s: %s", settingSources["admin-token"], err)
+	}
+	if token == "" {
+		return usageError("The admin token from %s is empty.", settingSources["admin-token"])
+	}
+	viper.Set("admin-token", token)
+	return nil
+}
+
+func readTokenFile(filename string) (string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// runTokenCommand runs the shell command and returns the first line of its output.
+func runTokenCommand(command string) (string, error) {
+	var out bytes.Buffer
+	c := exec.Command("sh", "-c", command)
+	c.Stdin = os.Stdin
+	c.Stdout = &out
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.SplitN(out.String(), "\n", 2)[0]), nil
+}
+
+// explainSettings prints the effective settings with their sources.
+func explainSettings() {
+	resolveAdminToken()
+
+	fmt.Println("Precedence: flag, environment, token file, token command, token store, profile, config file, default")
+	fmt.Println("Settings:")
+	profile := activeProfileName()
+	profileSource := sourceDefault
+	if profileName != "" {
+		profileSource = "flag --profile"
+	} else if os.Getenv(profileEnvVar) != "" {
+		profileSource = "environment $" + profileEnvVar
+	} else if profile != "" {
+		profileSource = fmt.Sprintf(`config file "%s"`, configFilepath())
+	}
+	fmt.Printf("\t%-20s %-24s %s\n", "profile", profile, profileSource)
+
+	for _, key := range explainKeys {
+		value := viper.GetString(key)
+		if value == "" {
+			continue
+		}
+		if key == "admin-token" {
+			value = maskToken(value)
+		}
+		fmt.Printf("\t%-20s %-24s %s\n", key, value, settingSource(key))
+	}
+}
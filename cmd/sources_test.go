@@ -0,0 +1,46 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReplaceTokenSource(t *testing.T) {
+	section := map[string]interface{}{
+		"admin-token":      "old",
+		cfgKeyTokenFile:    "/run/secrets/token",
+		cfgKeyTokenCommand: "pass show dns",
+		"domain":           "example.com",
+	}
+	replaceTokenSource(section, cfgKeyTokenRef, "work")
+	want := map[string]interface{}{cfgKeyTokenRef: "work", "domain": "example.com"}
+	if !reflect.DeepEqual(section, want) {
+		t.Errorf("section = %v, want %v", section, want)
+	}
+
+	replaceTokenSource(section, "admin-token", "new")
+	want = map[string]interface{}{"admin-token": "new", "domain": "example.com"}
+	if !reflect.DeepEqual(section, want) {
+		t.Errorf("section = %v, want %v", section, want)
+	}
+}
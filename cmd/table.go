@@ -0,0 +1,270 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/olekukonko/tablewriter"
+)
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+
+	minColumnWidth = 8
+	ellipsis       = "..."
+)
+
+var tableWide bool
+var tableWrap bool
+var tableNoHeaders bool
+var tableColor string
+
+var typeColors = map[string]string{
+	typeA:     "\x1b[32m",
+	typeAAAA:  "\x1b[36m",
+	typeCNAME: "\x1b[33m",
+	typeMX:    "\x1b[35m",
+	typeNS:    "\x1b[31m",
+	typeSOA:   "\x1b[1m",
+	typeSRV:   "\x1b[34m",
+	typeTXT:   "\x1b[94m",
+}
+
+func printTable(records []*api.Record, props string) {
+	parsedProps := parseCommaSep(props)
+	header := make([]string, len(parsedProps))
+	var err error
+	for i, prop := range parsedProps {
+		if header[i], err = getHeader(prop); err != nil {
+			throwError(err)
+		}
+	}
+
+	rows := make([][]string, len(records))
+	for i, rec := range records {
+		rows[i] = make([]string, len(parsedProps))
+		for q, prop := range parsedProps {
+			if rows[i][q], err = getValue(prop, rec); err != nil {
+				throwError(err)
+			}
+		}
+	}
+
+	color := useColor()
+	if !isTerminal(os.Stdout) {
+		var colorize func(col int, cell string) string
+		if color {
+			colorize = func(col int, cell string) string {
+				if strings.ToLower(parsedProps[col]) == propType {
+					return colorizeType(cell)
+				}
+				return cell
+			}
+		}
+		printAlignedTable(header, rows, colorize)
+		return
+	}
+
+	if !tableWide {
+		widths := fitColumns(columnWidths(header, rows), getTerminalWidth())
+		if tableWrap {
+			rows = wrapRows(rows, widths)
+		} else {
+			rows = truncateRows(rows, widths)
+		}
+	}
+	if color {
+		for q, prop := range parsedProps {
+			if strings.ToLower(prop) == propType {
+				for _, row := range rows {
+					row[q] = colorizeType(row[q])
+				}
+			}
+		}
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetColWidth(getTerminalWidth())
+	if !tableNoHeaders {
+		table.SetHeader(header)
+	}
+	for _, row := range rows {
+		table.Append(row)
+	}
+	table.Render()
+}
+
+// printPlainTable prints columns aligned with spaces, used when stdout is not a terminal.
+func printPlainTable(header []string, rows [][]string) {
+	printAlignedTable(header, rows, nil)
+}
+
+// printAlignedTable prints columns aligned with spaces, the cells are colorized after the alignment
+// so the escape sequences do not shift the columns.
+func printAlignedTable(header []string, rows [][]string, colorize func(col int, cell string) string) {
+	upper := make([]string, len(header))
+	for i, h := range header {
+		upper[i] = strings.ToUpper(h)
+	}
+	widths := columnWidths(upper, rows)
+	printRow := func(row []string, colored bool) {
+		var line bytes.Buffer
+		for i, cell := range row {
+			text := cell
+			if colored && colorize != nil {
+				text = colorize(i, cell)
+			}
+			line.WriteString(text)
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+2))
+			}
+		}
+		fmt.Println(line.String())
+	}
+	if !tableNoHeaders {
+		printRow(upper, false)
+	}
+	for _, row := range rows {
+		printRow(row, true)
+	}
+}
+
+func useColor() bool {
+	switch tableColor {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	default:
+		return os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	}
+}
+
+func colorizeType(t string) string {
+	if color, ok := typeColors[strings.ToUpper(strings.TrimSpace(t))]; ok {
+		return color + t + "\x1b[0m"
+	}
+	return t
+}
+
+func columnWidths(header []string, rows [][]string) []int {
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = utf8.RuneCountInString(h)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if w := utf8.RuneCountInString(cell); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+	return widths
+}
+
+// fitColumns shrinks the widest columns until the table with its borders fits into the width.
+func fitColumns(widths []int, width int) []int {
+	available := width - 3*len(widths) - 1
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+	for total > available {
+		widest := 0
+		for i, w := range widths {
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= minColumnWidth {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+	return widths
+}
+
+func truncateRows(rows [][]string, widths []int) [][]string {
+	for _, row := range rows {
+		for i, cell := range row {
+			row[i] = truncateString(cell, widths[i])
+		}
+	}
+	return rows
+}
+
+func truncateString(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	if width <= len(ellipsis) {
+		return string(runes[:width])
+	}
+	return string(runes[:width-len(ellipsis)]) + ellipsis
+}
+
+// wrapRows splits the cells wider than their columns into continuation rows.
+func wrapRows(rows [][]string, widths []int) [][]string {
+	var wrapped [][]string
+	for _, row := range rows {
+		lines := make([][]string, len(row))
+		height := 1
+		for i, cell := range row {
+			lines[i] = wrapString(cell, widths[i])
+			if len(lines[i]) > height {
+				height = len(lines[i])
+			}
+		}
+		for l := 0; l < height; l++ {
+			line := make([]string, len(row))
+			for i := range row {
+				if l < len(lines[i]) {
+					line[i] = lines[i][l]
+				}
+			}
+			wrapped = append(wrapped, line)
+		}
+	}
+	return wrapped
+}
+
+func wrapString(s string, width int) []string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return []string{s}
+	}
+	var lines []string
+	for len(runes) > width {
+		lines = append(lines, string(runes[:width]))
+		runes = runes[width:]
+	}
+	return append(lines, string(runes))
+}
@@ -0,0 +1,297 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const cfgKeyTemplates = "templates"
+
+var templateYes bool
+var templateDryRun bool
+
+type templateParam struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+}
+
+type templateRecord struct {
+	Type      string `json:"type"`
+	Subdomain string `json:"subdomain"`
+	Content   string `json:"content"`
+	Priority  string `json:"priority,omitempty"`
+	TTL       int    `json:"ttl,omitempty"`
+	Weight    int    `json:"weight,omitempty"`
+	Port      int    `json:"port,omitempty"`
+	Target    string `json:"target,omitempty"`
+}
+
+// recordTemplate is a named set of records with "{param}" placeholders
+type recordTemplate struct {
+	Description string           `json:"description,omitempty"`
+	Params      []templateParam  `json:"params,omitempty"`
+	Records     []templateRecord `json:"records"`
+}
+
+// templateCmd represents the template command
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Add or remove the records of common service providers",
+	Long: `Templates are named sets of records, e.g. the MX, SPF and DKIM records of a mail provider.
+Besides the built-in templates, custom ones can be defined in the "templates" section of the config file:
+
+    "templates": {
+        "office": {
+            "description": "Office VPN",
+            "params": [{"name": "ip"}],
+            "records": [{"type": "A", "subdomain": "vpn", "content": "{ip}"}]
+        }
+    }`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "The list of the templates",
+	Run: func(cmd *cobra.Command, args []string) {
+		templates := allTemplates()
+		names := make([]string, 0, len(templates))
+		for name := range templates {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("  %-20s %s\n", name, templates[name].Description)
+		}
+	},
+}
+
+var templateShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Show the parameters and records of the template",
+	Run: func(cmd *cobra.Command, args []string) {
+		name, tpl := templateArg(args)
+		fmt.Printf("%s: %s\n", name, tpl.Description)
+		if len(tpl.Params) > 0 {
+			fmt.Println("Parameters:")
+			for _, p := range tpl.Params {
+				if p.Default != "" {
+					fmt.Printf("  %-12s %s (default: %s)\n", p.Name, p.Description, p.Default)
+				} else {
+					fmt.Printf("  %-12s %s\n", p.Name, p.Description)
+				}
+			}
+		}
+		fmt.Println("Records:")
+		for _, r := range tpl.Records {
+			fmt.Printf("  %-24s %-6s %s %s\n", r.Subdomain, r.Type, r.Priority, r.Content)
+		}
+	},
+}
+
+var templateApplyCmd = &cobra.Command{
+	Use:   "apply <name> [param=value]...",
+	Short: "Add the records of the template",
+	Run: func(cmd *cobra.Command, args []string) {
+		checkRequiredSettings()
+		name, tpl := templateArg(args)
+		domain := viper.GetString("domain")
+		defer lockDomain(domain)()
+		records, err := tpl.render(domain, parseTemplateParams(args[1:]))
+		if err != nil {
+			throwError(err)
+		}
+
+		live := liveRecordKeys(domain)
+		var missing []api.Record
+		for i := range records {
+			if _, ok := live[recordKey(&records[i])]; ok {
+				fmt.Printf("  = %s\n", formatRecord(&records[i]))
+			} else {
+				fmt.Printf("  + %s\n", formatRecord(&records[i]))
+				missing = append(missing, records[i])
+			}
+		}
+		if len(missing) == 0 {
+			fmt.Printf("All records of the template \"%s\" are already present\n", name)
+			return
+		}
+		if templateDryRun || (!templateYes && !confirm(fmt.Sprintf("Add %d record(s) to %s?", len(missing), domain))) {
+			return
+		}
+
+		for i := range missing {
+			if _, err := dnsProvider.Create(domain, &missing[i]); err != nil {
+				throwError(err)
+			}
+			fmt.Printf("Record successfully created: %s\n", formatRecord(&missing[i]))
+		}
+	},
+}
+
+var templateRemoveCmd = &cobra.Command{
+	Use:   "remove <name> [param=value]...",
+	Short: "Delete the records of the template",
+	Run: func(cmd *cobra.Command, args []string) {
+		checkRequiredSettings()
+		name, tpl := templateArg(args)
+		domain := viper.GetString("domain")
+		defer lockDomain(domain)()
+		records, err := tpl.render(domain, parseTemplateParams(args[1:]))
+		if err != nil {
+			throwError(err)
+		}
+
+		live := liveRecordKeys(domain)
+		var present []*api.Record
+		for i := range records {
+			if r, ok := live[recordKey(&records[i])]; ok {
+				fmt.Printf("  - %s\n", formatRecord(r))
+				present = append(present, r)
+			}
+		}
+		if len(present) == 0 {
+			fmt.Printf("No records of the template \"%s\" are present\n", name)
+			return
+		}
+		if templateDryRun || (!templateYes && !confirm(fmt.Sprintf("Delete %d record(s) from %s?", len(present), domain))) {
+			return
+		}
+		verifyUnchanged(domain, present)
+
+		for _, r := range present {
+			if _, err := dnsProvider.Delete(domain, r.RecordId); err != nil {
+				throwError(err)
+			}
+			fmt.Printf("Record successfully deleted: %s\n", formatRecord(r))
+		}
+	},
+}
+
+// allTemplates returns the built-in templates and the ones defined in the config file.
+func allTemplates() map[string]recordTemplate {
+	templates := make(map[string]recordTemplate, len(builtinTemplates))
+	for name, tpl := range builtinTemplates {
+		templates[name] = tpl
+	}
+	for name, raw := range viper.GetStringMap(cfgKeyTemplates) {
+		var tpl recordTemplate
+		data, err := json.Marshal(raw)
+		if err == nil {
+			err = json.Unmarshal(data, &tpl)
+		}
+		if err != nil {
+			throwError(usageError(`Invalid template "%s" in the config file: %s`, name, err))
+		}
+		templates[name] = tpl
+	}
+	return templates
+}
+
+func templateArg(args []string) (string, recordTemplate) {
+	if len(args) < 1 {
+		throwError(usageError("Template name is required."))
+	}
+	tpl, ok := allTemplates()[args[0]]
+	if !ok {
+		throwError(usageError(`Unknown template "%s", see "template list".`, args[0]))
+	}
+	return args[0], tpl
+}
+
+func parseTemplateParams(args []string) map[string]string {
+	params := make(map[string]string, len(args))
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			throwError(usageError(`Invalid template parameter "%s", expected name=value.`, arg))
+		}
+		params[parts[0]] = parts[1]
+	}
+	return params
+}
+
+// render substitutes the parameters into the records of the template.
+func (t recordTemplate) render(domain string, params map[string]string) ([]api.Record, error) {
+	pairs := []string{"{domain}", domain, "{domain_dashed}", strings.Replace(domain, ".", "-", -1)}
+	for _, p := range t.Params {
+		value, ok := params[p.Name]
+		if !ok {
+			value = p.Default
+		}
+		if value == "" {
+			return nil, usageError(`Template parameter "%s" (%s) is required.`, p.Name, p.Description)
+		}
+		pairs = append(pairs, "{"+p.Name+"}", value)
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	records := make([]api.Record, len(t.Records))
+	for i, tr := range t.Records {
+		records[i] = api.Record{
+			RecordType: strings.ToUpper(tr.Type),
+			Subdomain:  replacer.Replace(tr.Subdomain),
+			Content:    replacer.Replace(tr.Content),
+			TTL:        tr.TTL,
+			Weight:     tr.Weight,
+			Port:       tr.Port,
+			Target:     replacer.Replace(tr.Target),
+		}
+		if tr.Priority != "" {
+			records[i].Priority = tr.Priority
+		}
+	}
+	return records, nil
+}
+
+// liveRecordKeys fetches the records of the domain indexed by recordKey.
+func liveRecordKeys(domain string) map[string]*api.Record {
+	list, err := dnsProvider.List(domain)
+	if err != nil {
+		throwError(err)
+	}
+	keys := make(map[string]*api.Record, len(list.Records))
+	for i := range list.Records {
+		keys[recordKey(&list.Records[i])] = &list.Records[i]
+	}
+	return keys
+}
+
+func init() {
+	RootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templateListCmd, templateShowCmd, templateApplyCmd, templateRemoveCmd)
+
+	for _, c := range []*cobra.Command{templateApplyCmd, templateRemoveCmd} {
+		c.Flags().BoolVarP(&templateYes, "yes", "y", false, "do not ask for confirmation")
+		c.Flags().BoolVarP(&templateDryRun, "dry-run", "n", false, "only show the changes")
+	}
+}
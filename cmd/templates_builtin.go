@@ -0,0 +1,86 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+// builtinTemplates is the library of the records required by the common service providers.
+// "{domain}" and "{domain_dashed}" (dots replaced with dashes) are always available.
+var builtinTemplates = map[string]recordTemplate{
+	"yandex360": {
+		Description: "Yandex 360 mail",
+		Params: []templateParam{
+			{Name: "code", Description: "domain verification code"},
+			{Name: "dkim", Description: "DKIM public key record (v=DKIM1; k=rsa; p=...)"},
+		},
+		Records: []templateRecord{
+			{Type: typeMX, Subdomain: "@", Content: "mx.yandex.net.", Priority: "10"},
+			{Type: typeTXT, Subdomain: "@", Content: "v=spf1 redirect=_spf.yandex.net"},
+			{Type: typeTXT, Subdomain: "@", Content: "yandex-verification: {code}"},
+			{Type: typeTXT, Subdomain: "mail._domainkey", Content: "{dkim}"},
+			{Type: typeCNAME, Subdomain: "mail", Content: "domain.mail.yandex.net."},
+		},
+	},
+	"google-workspace": {
+		Description: "Google Workspace mail",
+		Params: []templateParam{
+			{Name: "code", Description: "google-site-verification code"},
+			{Name: "dkim", Description: "DKIM public key record (v=DKIM1; k=rsa; p=...)"},
+		},
+		Records: []templateRecord{
+			{Type: typeMX, Subdomain: "@", Content: "smtp.google.com.", Priority: "1"},
+			{Type: typeTXT, Subdomain: "@", Content: "v=spf1 include:_spf.google.com ~all"},
+			{Type: typeTXT, Subdomain: "@", Content: "google-site-verification={code}"},
+			{Type: typeTXT, Subdomain: "google._domainkey", Content: "{dkim}"},
+		},
+	},
+	"microsoft365": {
+		Description: "Microsoft 365 mail",
+		Params: []templateParam{
+			{Name: "code", Description: "MS= verification code"},
+			{Name: "tenant", Description: "tenant name (<tenant>.onmicrosoft.com)"},
+		},
+		Records: []templateRecord{
+			{Type: typeMX, Subdomain: "@", Content: "{domain_dashed}.mail.protection.outlook.com.", Priority: "0"},
+			{Type: typeTXT, Subdomain: "@", Content: "v=spf1 include:spf.protection.outlook.com -all"},
+			{Type: typeTXT, Subdomain: "@", Content: "MS={code}"},
+			{Type: typeCNAME, Subdomain: "autodiscover", Content: "autodiscover.outlook.com."},
+			{Type: typeCNAME, Subdomain: "selector1._domainkey", Content: "selector1-{domain_dashed}._domainkey.{tenant}.onmicrosoft.com."},
+			{Type: typeCNAME, Subdomain: "selector2._domainkey", Content: "selector2-{domain_dashed}._domainkey.{tenant}.onmicrosoft.com."},
+			{Type: typeSRV, Subdomain: "_sip._tls", Content: "sipdir.online.lync.com.", Priority: "100", Weight: 1, Port: 443, Target: "sipdir.online.lync.com."},
+		},
+	},
+	"github-pages": {
+		Description: "GitHub Pages site on the apex and www",
+		Params: []templateParam{
+			{Name: "user", Description: "GitHub user or organization name"},
+		},
+		Records: []templateRecord{
+			{Type: typeA, Subdomain: "@", Content: "185.199.108.153"},
+			{Type: typeA, Subdomain: "@", Content: "185.199.109.153"},
+			{Type: typeA, Subdomain: "@", Content: "185.199.110.153"},
+			{Type: typeA, Subdomain: "@", Content: "185.199.111.153"},
+			{Type: typeAAAA, Subdomain: "@", Content: "2606:50c0:8000::153"},
+			{Type: typeAAAA, Subdomain: "@", Content: "2606:50c0:8001::153"},
+			{Type: typeAAAA, Subdomain: "@", Content: "2606:50c0:8002::153"},
+			{Type: typeAAAA, Subdomain: "@", Content: "2606:50c0:8003::153"},
+			{Type: typeCNAME, Subdomain: "www", Content: "{user}.github.io."},
+		},
+	},
+}
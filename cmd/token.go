@@ -0,0 +1,327 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lexty/yandex-dns-cli-manager/secrets"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	cfgKeyTokenRef   = "admin-token-ref"
+	cfgKeyTokenStore = "token-store"
+
+	tokenStoreFileName = ".yandexdns.tokens.json"
+	agentSocketName    = ".yandexdns.agent.sock"
+
+	passphraseEnvVar  = "YANDEX_DNS_PASSPHRASE"
+	agentSocketEnvVar = "YANDEX_DNS_AGENT_SOCK"
+)
+
+var tokenUse bool
+var tokenNewPassphrase bool
+var agentTimeout time.Duration
+
+// tokenCmd represents the settings token command
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage the encrypted admin token store",
+	Long: `Admin tokens can be kept encrypted in a local store and referred to by name
+with the "` + cfgKeyTokenRef + `" setting instead of "admin-token".
+
+The passphrase of the store is read from $` + passphraseEnvVar + `, from the agent
+started with "settings token agent" or from the terminal.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var tokenAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Encrypt and store an admin token",
+	Run: func(cmd *cobra.Command, args []string) {
+		name := tokenNameArg(args)
+		store := openTokenStore()
+		if _, ok := store.Tokens[name]; ok {
+			throwError(usageError(`Token "%s" already exists, use "settings token rotate".`, name))
+		}
+		token := promptToken()
+		if err := store.Set(name, token); err != nil {
+			throwError(err)
+		}
+		if err := store.Save(); err != nil {
+			throwError(err)
+		}
+		fmt.Printf("Token \"%s\" successfully stored\n", name)
+
+		if tokenUse {
+			useStoredToken(name, "")
+		}
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "The list of the stored tokens",
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := secrets.Open(tokenStorePath())
+		if err != nil {
+			throwError(err)
+		}
+		for _, name := range store.Names() {
+			fmt.Println(name)
+		}
+	},
+}
+
+var tokenRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove the stored token",
+	Run: func(cmd *cobra.Command, args []string) {
+		name := tokenNameArg(args)
+		store, err := secrets.Open(tokenStorePath())
+		if err != nil {
+			throwError(err)
+		}
+		if err := store.Remove(name); err != nil {
+			throwError(usageError(`Unknown token "%s".`, name))
+		}
+		if err := store.Save(); err != nil {
+			throwError(err)
+		}
+		fmt.Printf("Token \"%s\" successfully removed\n", name)
+	},
+}
+
+var tokenRotateCmd = &cobra.Command{
+	Use:   "rotate <name>",
+	Short: "Replace the stored token and re-encrypt the store",
+	Long: `Replaces the value of the stored token, re-encrypts the whole store with a new salt
+(and a new passphrase with --new-passphrase) and makes every profile that contains
+the old token in plaintext refer to the stored one.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		name := tokenNameArg(args)
+		store := openTokenStore()
+		oldToken, err := store.Get(name)
+		if err != nil {
+			throwError(usageError(`Unknown token "%s".`, name))
+		}
+		// the store may be unlocked by the agent, the passphrase is asked for and verified anyway
+		current, err := currentPassphrase()
+		if err != nil {
+			throwError(err)
+		}
+		token := promptToken()
+		if err := store.Set(name, token); err != nil {
+			throwError(err)
+		}
+
+		passphrase := current
+		if tokenNewPassphrase {
+			passphrase = promptNewPassphrase()
+		}
+		if err := store.Rekey(current, passphrase); err != nil {
+			throwError(err)
+		}
+		if err := store.Save(); err != nil {
+			throwError(err)
+		}
+		fmt.Printf("Token \"%s\" successfully rotated\n", name)
+
+		useStoredToken(name, oldToken)
+	},
+}
+
+var tokenAgentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Keep the store unlocked for a while",
+	Long: `Asks for the passphrase once and hands out the key of the store to other invocations
+through a unix socket ($` + agentSocketEnvVar + ` or $HOME/` + agentSocketName + `) until the timeout expires.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		store, err := secrets.Open(tokenStorePath())
+		if err != nil {
+			throwError(err)
+		}
+		if store.IsNew() {
+			throwError(usageError(`The token store is empty, add a token with "settings token add".`))
+		}
+		passphrase, err := readPassword("Passphrase: ")
+		if err != nil {
+			throwError(err)
+		}
+		if err := store.Unlock(passphrase); err != nil {
+			throwError(err)
+		}
+		fmt.Fprintf(os.Stderr, "Agent is listening on %s for %s\n", agentSocketPath(), agentTimeout)
+		if err := secrets.ServeAgent(agentSocketPath(), store.Key(), agentTimeout); err != nil {
+			throwError(err)
+		}
+	},
+}
+
+func tokenNameArg(args []string) string {
+	if len(args) != 1 || args[0] == "" {
+		throwError(usageError("Token name is required."))
+	}
+	return args[0]
+}
+
+func tokenStorePath() string {
+	if path := viper.GetString(cfgKeyTokenStore); path != "" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(getDefaultCfgFilepath()), tokenStoreFileName)
+}
+
+func agentSocketPath() string {
+	if path := os.Getenv(agentSocketEnvVar); path != "" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(getDefaultCfgFilepath()), agentSocketName)
+}
+
+// openTokenStore opens and unlocks the token store, a new store asks for a new passphrase.
+func openTokenStore() *secrets.Store {
+	store, err := unlockTokenStore()
+	if err != nil {
+		throwError(err)
+	}
+	return store
+}
+
+// unlockTokenStore opens the store with the key of the agent or the passphrase.
+func unlockTokenStore() (*secrets.Store, error) {
+	store, err := secrets.Open(tokenStorePath())
+	if err != nil {
+		return nil, err
+	}
+	if store.IsNew() {
+		err = store.Unlock(promptNewPassphrase())
+	} else if key, agentErr := secrets.AgentKey(agentSocketPath()); agentErr == nil && store.UnlockWithKey(key) == nil {
+		return store, nil
+	} else {
+		var passphrase string
+		if passphrase, err = currentPassphrase(); err == nil {
+			err = store.Unlock(passphrase)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func currentPassphrase() (string, error) {
+	if passphrase := os.Getenv(passphraseEnvVar); passphrase != "" {
+		return passphrase, nil
+	}
+	return readPassword("Passphrase: ")
+}
+
+func promptNewPassphrase() string {
+	if passphrase := os.Getenv(passphraseEnvVar); passphrase != "" && !tokenNewPassphrase {
+		return passphrase
+	}
+	passphrase, err := readPassword("New passphrase: ")
+	if err != nil {
+		throwError(err)
+	}
+	confirm, err := readPassword("Repeat passphrase: ")
+	if err != nil {
+		throwError(err)
+	}
+	if passphrase == "" || passphrase != confirm {
+		throwError(usageError("Passphrases are empty or do not match."))
+	}
+	return passphrase
+}
+
+func promptToken() string {
+	token, err := readPassword("Admin token: ")
+	if err != nil {
+		throwError(err)
+	}
+	if token == "" {
+		throwError(usageError("Admin token is empty."))
+	}
+	return token
+}
+
+// useStoredToken makes the settings refer to the stored token. With --use the selected profile
+// (or the top level settings) is changed, otherwise only the profiles containing the old token.
+func useStoredToken(name, oldToken string) {
+	cfg, err := readConfigFile()
+	if err != nil {
+		throwError(err)
+	}
+
+	sections := []map[string]interface{}{cfg}
+	for _, profile := range configProfiles(cfg) {
+		if section, ok := profile.(map[string]interface{}); ok {
+			sections = append(sections, section)
+		}
+	}
+	if tokenUse {
+		sections = []map[string]interface{}{selectedConfigSection(cfg)}
+	}
+
+	changed := 0
+	for _, section := range sections {
+		if tokenUse {
+			replaceTokenSource(section, cfgKeyTokenRef, name)
+			changed++
+		} else if oldToken != "" && section["admin-token"] == oldToken {
+			delete(section, "admin-token")
+			section[cfgKeyTokenRef] = name
+			changed++
+		}
+	}
+	if changed == 0 {
+		return
+	}
+	if err := writeConfigFile(cfg); err != nil {
+		throwError(err)
+	}
+	fmt.Printf("Settings now refer to the stored token \"%s\" in %d place(s)\n", name, changed)
+}
+
+// maskToken hides the middle of the token for display.
+func maskToken(token string) string {
+	if len(token) <= 8 {
+		return "****"
+	}
+	return token[:4] + "****" + token[len(token)-4:]
+}
+
+func init() {
+	settingsCmd.AddCommand(tokenCmd)
+	tokenCmd.AddCommand(tokenAddCmd, tokenListCmd, tokenRemoveCmd, tokenRotateCmd, tokenAgentCmd)
+
+	tokenAddCmd.Flags().BoolVarP(&tokenUse, "use", "u", false, "refer to the token in the settings (or in the profile selected with --profile)")
+	tokenRotateCmd.Flags().BoolVarP(&tokenNewPassphrase, "new-passphrase", "n", false, "change the passphrase of the store")
+	tokenAgentCmd.Flags().DurationVarP(&agentTimeout, "timeout", "t", 15*time.Minute, "how long the store stays unlocked")
+}
@@ -0,0 +1,137 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+)
+
+const apexLabel = "@"
+
+// treeNode is a single label of the zone hierarchy
+type treeNode struct {
+	label    string
+	records  []*api.Record
+	children map[string]*treeNode
+}
+
+func newTreeNode(label string) *treeNode {
+	return &treeNode{label: label, children: make(map[string]*treeNode)}
+}
+
+// buildTree places the records into the label hierarchy rooted at the domain.
+func buildTree(records []*api.Record) *treeNode {
+	root := newTreeNode("")
+	for _, r := range records {
+		node := root
+		if r.Subdomain != "" && r.Subdomain != apexLabel {
+			labels := strings.Split(strings.TrimSuffix(r.Subdomain, "."), ".")
+			for i := len(labels) - 1; i >= 0; i-- {
+				child, ok := node.children[labels[i]]
+				if !ok {
+					child = newTreeNode(labels[i])
+					node.children[labels[i]] = child
+				}
+				node = child
+			}
+		}
+		node.records = append(node.records, r)
+	}
+	collapseTree(root)
+	return root
+}
+
+// collapseTree merges intermediate labels without records into their only child.
+func collapseTree(n *treeNode) {
+	for _, child := range n.sortedChildren() {
+		key := child.label
+		collapseTree(child)
+		if len(child.records) == 0 && len(child.children) == 1 {
+			for _, grandchild := range child.children {
+				grandchild.label = grandchild.label + "." + child.label
+				delete(n.children, key)
+				n.children[grandchild.label] = grandchild
+			}
+		}
+	}
+}
+
+func (n *treeNode) sortedChildren() []*treeNode {
+	keys := make([]string, 0, len(n.children))
+	for key := range n.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	children := make([]*treeNode, len(keys))
+	for i, key := range keys {
+		children[i] = n.children[key]
+	}
+	return children
+}
+
+func (n *treeNode) marks() string {
+	var marks []string
+	if strings.HasPrefix(n.label, "*") {
+		marks = append(marks, "wildcard")
+	}
+	for _, r := range n.records {
+		if strings.ToUpper(r.RecordType) == typeNS {
+			marks = append(marks, "delegated")
+			break
+		}
+	}
+	if len(marks) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(marks, ", ") + "]"
+}
+
+func printTree(records []*api.Record, domain string) {
+	fmt.Println(domain)
+	printTreeNode(buildTree(records), "")
+}
+
+func printTreeNode(n *treeNode, prefix string) {
+	children := n.sortedChildren()
+	recPrefix := prefix + "    "
+	if len(children) > 0 {
+		recPrefix = prefix + "│   "
+	}
+	for _, r := range n.records {
+		content := r.Content
+		if priority := api.PriorityString(r.Priority); priority != "" {
+			content = priority + " " + content
+		}
+		fmt.Printf("%s%-6s %s\n", recPrefix, r.RecordType, content)
+	}
+	for i, child := range children {
+		branch, next := "├── ", "│   "
+		if i == len(children)-1 {
+			branch, next = "└── ", "    "
+		}
+		fmt.Printf("%s%s%s%s\n", prefix, branch, child.label, child.marks())
+		printTreeNode(child, prefix+next)
+	}
+}
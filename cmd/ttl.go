@@ -0,0 +1,328 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	ttlStateFileName = ".yandexdns.ttl.json"
+	migrationTTL     = 300
+)
+
+var ttlMatch string
+var ttlTypes string
+var ttlYes bool
+var ttlDryRun bool
+var ttlStateFile string
+
+// ttlSaved is the original TTL of a changed record
+type ttlSaved struct {
+	Subdomain string `json:"subdomain"`
+	Type      string `json:"type"`
+	Content   string `json:"content"`
+	TTL       int    `json:"ttl"`
+}
+
+// ttlDomainState tracks the changed TTLs of a domain between the invocations
+type ttlDomainState struct {
+	Lowered time.Time        `json:"lowered,omitempty"`
+	SafeAt  time.Time        `json:"safe_at,omitempty"`
+	Records map[int]ttlSaved `json:"records"`
+}
+
+// ttlCmd represents the ttl command
+var ttlCmd = &cobra.Command{
+	Use:   "ttl <seconds>",
+	Short: "Change the TTL of many records and restore it",
+	Long: `Sets the TTL of all records matching --match and --types. The previous TTLs are saved
+and "ttl restore" puts them back. Before moving a service:
+
+  ttl migrate --match www     # lower the TTLs and show when it is safe to switch
+  ttl status                  # the saved TTLs and the time left
+  edit --id 123 --content 192.0.2.2
+  ttl restore --match www     # raise the TTLs back`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			throwError(usageError("The TTL in seconds is required."))
+		}
+		setTTL(parseTTLArg(args[0]))
+	},
+}
+
+var ttlMigrateCmd = &cobra.Command{
+	Use:   "migrate [seconds]",
+	Short: "Lower the TTLs before a migration and report the safe cutover time",
+	Run: func(cmd *cobra.Command, args []string) {
+		ttl := migrationTTL
+		if len(args) > 0 {
+			ttl = parseTTLArg(args[0])
+		}
+		if state := setTTL(ttl); state != nil {
+			printCutover(state)
+		}
+	},
+}
+
+var ttlRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore the TTLs saved by ttl and ttl migrate",
+	Run: func(cmd *cobra.Command, args []string) {
+		checkRequiredSettings()
+		domain := viper.GetString("domain")
+		defer lockDomain(domain)()
+		states := readTTLState()
+		state := states[domain]
+		if state == nil || len(state.Records) == 0 {
+			fmt.Printf("No saved TTLs of %s\n", domain)
+			return
+		}
+		list, err := dnsProvider.List(domain)
+		if err != nil {
+			throwError(err)
+		}
+		live := make(map[int]*api.Record, len(list.Records))
+		for i := range list.Records {
+			live[list.Records[i].RecordId] = &list.Records[i]
+		}
+
+		var ids []int
+		var touched []*api.Record
+		for _, id := range sortedTTLIds(state) {
+			saved := state.Records[id]
+			if !matchesTTLSelector(saved.Subdomain, saved.Type) {
+				continue
+			}
+			ids = append(ids, id)
+			if r, ok := live[id]; ok {
+				fmt.Printf("  ~ %s -> ttl=%d\n", formatRecord(r), saved.TTL)
+				touched = append(touched, r)
+			} else {
+				fmt.Printf("  ! #%d %s %s %s no longer exists\n", id, saved.Subdomain, saved.Type, saved.Content)
+			}
+		}
+		if len(ids) == 0 || ttlDryRun || (!ttlYes && !confirm(fmt.Sprintf("Restore the TTL of %d record(s) of %s?", len(ids), domain))) {
+			return
+		}
+		verifyUnchanged(domain, touched)
+
+		for _, id := range ids {
+			if r, ok := live[id]; ok && r.TTL != state.Records[id].TTL {
+				update := *r
+				update.TTL = state.Records[id].TTL
+				if _, err := dnsProvider.Update(domain, &update); err != nil {
+					throwError(err)
+				}
+			}
+			delete(state.Records, id)
+			writeTTLState(states)
+		}
+		if len(state.Records) == 0 {
+			delete(states, domain)
+			writeTTLState(states)
+		}
+		fmt.Printf("Restored the TTL of %d record(s)\n", len(ids))
+	},
+}
+
+var ttlStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the saved TTLs and the safe cutover time",
+	Run: func(cmd *cobra.Command, args []string) {
+		domain := viper.GetString("domain")
+		if domain == "" {
+			throwError(cliError{exitUsage, errorCodeUsage, "--domain is not set", apiErrorHints["no_domain"]})
+		}
+		state := readTTLState()[domain]
+		if state == nil || len(state.Records) == 0 {
+			fmt.Printf("No saved TTLs of %s\n", domain)
+			return
+		}
+		for _, id := range sortedTTLIds(state) {
+			saved := state.Records[id]
+			fmt.Printf("  #%d %s %s %s saved ttl=%d\n", id, saved.Subdomain, saved.Type, saved.Content, saved.TTL)
+		}
+		printCutover(state)
+	},
+}
+
+// setTTL changes the TTL of the selected records saving the original values, it returns the state of the domain.
+func setTTL(ttl int) *ttlDomainState {
+	checkRequiredSettings()
+	domain := viper.GetString("domain")
+	defer lockDomain(domain)()
+	list, err := dnsProvider.List(domain)
+	if err != nil {
+		throwError(err)
+	}
+
+	var selected []*api.Record
+	for i := range list.Records {
+		r := &list.Records[i]
+		if matchesTTLSelector(r.Subdomain, r.RecordType) && r.TTL != ttl {
+			fmt.Printf("  ~ %s -> ttl=%d\n", formatRecord(r), ttl)
+			selected = append(selected, r)
+		}
+	}
+	if len(selected) == 0 {
+		fmt.Println("No records to change")
+		return readTTLState()[domain]
+	}
+	if ttlDryRun || (!ttlYes && !confirm(fmt.Sprintf("Change the TTL of %d record(s) of %s?", len(selected), domain))) {
+		return nil
+	}
+	verifyUnchanged(domain, selected)
+
+	states := readTTLState()
+	state := states[domain]
+	if state == nil {
+		state = &ttlDomainState{Records: make(map[int]ttlSaved)}
+		states[domain] = state
+	}
+	for _, r := range selected {
+		saved, ok := state.Records[r.RecordId]
+		if !ok {
+			saved = ttlSaved{r.Subdomain, strings.ToUpper(r.RecordType), r.Content, r.TTL}
+			state.Records[r.RecordId] = saved
+		}
+		update := *r
+		update.TTL = ttl
+		if _, err := dnsProvider.Update(domain, &update); err != nil {
+			writeTTLState(states)
+			throwError(err)
+		}
+		// resolvers may keep the old answers for the original TTL
+		if ttl < saved.TTL {
+			now := time.Now()
+			state.Lowered = now
+			if safeAt := now.Add(time.Duration(saved.TTL) * time.Second); safeAt.After(state.SafeAt) {
+				state.SafeAt = safeAt
+			}
+		}
+	}
+	writeTTLState(states)
+	fmt.Printf("Changed the TTL of %d record(s), the previous values are saved\n", len(selected))
+	return state
+}
+
+func printCutover(state *ttlDomainState) {
+	if state.SafeAt.IsZero() {
+		return
+	}
+	if left := state.SafeAt.Sub(time.Now()); left > 0 {
+		fmt.Printf("Safe to switch after %s (in %s)\n", state.SafeAt.Format(time.RFC3339), left/time.Second*time.Second)
+	} else {
+		fmt.Printf("Safe to switch since %s\n", state.SafeAt.Format(time.RFC3339))
+	}
+}
+
+// matchesTTLSelector reports whether the record is selected by --match and --types, SOA records are never selected.
+func matchesTTLSelector(subdomain, recordType string) bool {
+	recordType = strings.ToUpper(recordType)
+	if recordType == typeSOA {
+		return false
+	}
+	if ttlTypes != "" && !containsString(splitList(strings.ToUpper(ttlTypes)), recordType) {
+		return false
+	}
+	if subdomain == "" {
+		subdomain = apexLabel
+	}
+	matched, err := path.Match(ttlMatch, subdomain)
+	if err != nil {
+		throwError(usageError(`Invalid pattern "%s": %s`, ttlMatch, err))
+	}
+	return matched
+}
+
+func parseTTLArg(arg string) int {
+	ttl, err := strconv.Atoi(arg)
+	if err != nil || ttl <= 0 {
+		throwError(usageError(`Invalid TTL "%s".`, arg))
+	}
+	return ttl
+}
+
+func sortedTTLIds(state *ttlDomainState) []int {
+	ids := make([]int, 0, len(state.Records))
+	for id := range state.Records {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func ttlStatePath() string {
+	if ttlStateFile != "" {
+		return ttlStateFile
+	}
+	return filepath.Join(filepath.Dir(getDefaultCfgFilepath()), ttlStateFileName)
+}
+
+func readTTLState() map[string]*ttlDomainState {
+	states := make(map[string]*ttlDomainState)
+	data, err := ioutil.ReadFile(ttlStatePath())
+	if os.IsNotExist(err) {
+		return states
+	}
+	if err != nil {
+		throwError(err)
+	}
+	if err := json.Unmarshal(data, &states); err != nil {
+		throwError(fmt.Errorf(`Cannot parse state file "%s": %s`, ttlStatePath(), err))
+	}
+	return states
+}
+
+func writeTTLState(states map[string]*ttlDomainState) {
+	data, err := json.MarshalIndent(states, "", "    ")
+	if err != nil {
+		throwError(err)
+	}
+	if err := ioutil.WriteFile(ttlStatePath(), data, 0600); err != nil {
+		throwError(err)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(ttlCmd)
+	ttlCmd.AddCommand(ttlMigrateCmd, ttlRestoreCmd, ttlStatusCmd)
+
+	ttlCmd.PersistentFlags().StringVarP(&ttlMatch, "match", "m", "*", `subdomain pattern of the records, e.g. "*.api" ("@" is the domain itself)`)
+	ttlCmd.PersistentFlags().StringVarP(&ttlTypes, "type", "t", "", "comma separated record types (default is all but SOA)")
+	ttlCmd.PersistentFlags().BoolVarP(&ttlYes, "yes", "y", false, "do not ask for confirmation")
+	ttlCmd.PersistentFlags().BoolVarP(&ttlDryRun, "dry-run", "n", false, "only show the changes")
+	ttlCmd.PersistentFlags().StringVar(&ttlStateFile, "state", "", "file keeping the saved TTLs (default is $HOME/"+ttlStateFileName+")")
+}
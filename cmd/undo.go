@@ -0,0 +1,302 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/lexty/yandex-dns-cli-manager/journal"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var undoLast int
+var undoForce bool
+var undoYes bool
+var undoDryRun bool
+
+// undoStep is the inverse of a journal entry
+type undoStep struct {
+	entry   journal.Entry
+	problem string // why the inverse cannot be applied safely
+}
+
+// undoCmd represents the undo command
+var undoCmd = &cobra.Command{
+	Use:   "undo [seq]...",
+	Short: "Revert the recent changes recorded in the journal",
+	Long: `Reverts the last change of the domain (--last for more) or the journal entries with the sequence numbers
+shown by "history": created records are deleted, deleted records are added again and edited records get their
+previous values. Before reverting, the live records are compared with the state after the change; changes made
+later are not overwritten unless --force is given. Entries without the previous state of the record,
+e.g. when it could not be listed before the change, are never reverted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkRequiredSettings()
+		defer lockDomain(viper.GetString("domain"))()
+		entries, err := journal.Open(journalPath()).Entries()
+		if err != nil {
+			throwError(err)
+		}
+		// the entries of a tampered journal must not drive changes of the live records
+		if err := journal.Verify(entries); err != nil {
+			throwError(cliError{exitMismatch, errorCodeMismatch, "the journal is broken: " + err.Error(), "the journal was edited or truncated outside of the CLI, move it aside to start a new journal"})
+		}
+		selected := selectUndoEntries(entries, args, viper.GetString("domain"))
+		if len(selected) == 0 {
+			fmt.Println("Nothing to undo")
+			return
+		}
+
+		steps := make([]undoStep, len(selected))
+		lists := make(map[string][]api.Record)
+		touched := make(map[string][]*api.Record)
+		for i, e := range selected {
+			records, ok := lists[e.Domain]
+			if !ok {
+				if e.Domain != viper.GetString("domain") {
+					defer lockDomain(e.Domain)()
+				}
+				list, err := dnsProvider.List(e.Domain)
+				if err != nil {
+					throwError(err)
+				}
+				records, lists[e.Domain] = list.Records, list.Records
+				for j := range list.Records {
+					if undoRecordIds(selected)[list.Records[j].RecordId] {
+						touched[e.Domain] = append(touched[e.Domain], &list.Records[j])
+					}
+				}
+			}
+			steps[i] = undoStep{e, checkUndo(e, records)}
+			// the older entries are checked against the records as they will be after this step
+			lists[e.Domain] = simulateUndo(e, records)
+			fmt.Printf("  #%d %s %s: %s\n", e.Seq, e.Domain, inverseOperation(e), describeChange(e))
+			if steps[i].problem != "" {
+				fmt.Printf("      ! %s\n", steps[i].problem)
+			}
+		}
+		if err := checkUndoSteps(steps, undoForce); err != nil {
+			throwError(err)
+		}
+		if undoDryRun || (!undoYes && !confirm(fmt.Sprintf("Revert %d change(s)?", len(steps)))) {
+			return
+		}
+		for domain, records := range touched {
+			verifyUnchanged(domain, records)
+		}
+
+		jp, _ := dnsProvider.(*journalProvider)
+		newIds := make(map[int]int)
+		for _, step := range steps {
+			if jp != nil {
+				jp.undoes = step.entry.Seq
+			}
+			if err := applyUndo(step.entry, newIds); err != nil {
+				throwError(err)
+			}
+			fmt.Printf("Reverted #%d\n", step.entry.Seq)
+		}
+	},
+}
+
+// selectUndoEntries returns the entries to undo, newest first: the entries with the given sequence numbers
+// or the last ones of the domain which are neither undone nor undos themselves.
+func selectUndoEntries(entries []journal.Entry, args []string, domain string) []journal.Entry {
+	undone := make(map[int]bool)
+	for _, e := range entries {
+		if e.Undoes != 0 {
+			undone[e.Undoes] = true
+		}
+	}
+
+	var selected []journal.Entry
+	if len(args) > 0 {
+		bySeq := make(map[int]journal.Entry, len(entries))
+		for _, e := range entries {
+			bySeq[e.Seq] = e
+		}
+		for _, arg := range args {
+			seq, err := strconv.Atoi(arg)
+			e, ok := bySeq[seq]
+			if err != nil || !ok {
+				throwError(usageError(`No journal entry "%s".`, arg))
+			}
+			if undone[seq] {
+				fmt.Fprintf(os.Stderr, "Warning: #%d is already undone\n", seq)
+			}
+			selected = append(selected, e)
+		}
+		sort.Sort(entriesNewestFirst(selected))
+		return selected
+	}
+
+	for i := len(entries) - 1; i >= 0 && len(selected) < undoLast; i-- {
+		e := entries[i]
+		if e.Domain == domain && e.Undoes == 0 && !undone[e.Seq] {
+			selected = append(selected, e)
+		}
+	}
+	return selected
+}
+
+// entriesNewestFirst orders the journal entries by sequence number descending
+type entriesNewestFirst []journal.Entry
+
+func (s entriesNewestFirst) Len() int           { return len(s) }
+func (s entriesNewestFirst) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s entriesNewestFirst) Less(i, j int) bool { return s[i].Seq > s[j].Seq }
+
+func inverseOperation(e journal.Entry) string {
+	switch e.Operation {
+	case journal.OpAdd:
+		return "delete"
+	case journal.OpDelete:
+		return "add again"
+	}
+	return "restore"
+}
+
+// checkUndo compares the live records with the state after the change.
+func checkUndo(e journal.Entry, live []api.Record) string {
+	var current *api.Record
+	for i := range live {
+		if live[i].RecordId == e.RecordId {
+			current = &live[i]
+		}
+	}
+	switch e.Operation {
+	case journal.OpAdd, journal.OpEdit:
+		if e.Operation == journal.OpEdit && e.Before == nil {
+			return "the state before the change is unknown"
+		}
+		if current == nil {
+			return fmt.Sprintf("record #%d no longer exists", e.RecordId)
+		}
+		if e.After != nil && !sameRecordState(current, e.After) {
+			return fmt.Sprintf("record #%d was changed since: %s", e.RecordId, formatRecord(current))
+		}
+	case journal.OpDelete:
+		if e.Before == nil {
+			return "the deleted record is unknown"
+		}
+		for i := range live {
+			if recordKey(&live[i]) == recordKey(e.Before) {
+				return fmt.Sprintf("the record exists again: %s", formatRecord(&live[i]))
+			}
+		}
+	}
+	return ""
+}
+
+// unknownBefore reports whether the entry lacks the record to restore, e.g. the record could not be listed
+// before the change. Such an entry cannot be reverted even with --force.
+func unknownBefore(e journal.Entry) bool {
+	return (e.Operation == journal.OpEdit || e.Operation == journal.OpDelete) && e.Before == nil
+}
+
+// checkUndoSteps fails if a step cannot be reverted or, unless forced, if the records were changed since.
+func checkUndoSteps(steps []undoStep, force bool) error {
+	changed := false
+	for _, step := range steps {
+		if unknownBefore(step.entry) {
+			return cliError{exitMismatch, errorCodeMismatch, fmt.Sprintf("#%d cannot be reverted: %s", step.entry.Seq, step.problem), "restore the record with edit or add"}
+		}
+		if step.problem != "" {
+			changed = true
+		}
+	}
+	if changed && !force {
+		return cliError{exitMismatch, errorCodeMismatch, "the records were changed after the journal entries", "use --force to revert anyway"}
+	}
+	return nil
+}
+
+// undoRecordIds returns the IDs of the records changed by the entries.
+func undoRecordIds(entries []journal.Entry) map[int]bool {
+	ids := make(map[int]bool, len(entries))
+	for _, e := range entries {
+		ids[e.RecordId] = true
+	}
+	return ids
+}
+
+// simulateUndo returns the records as they are after reverting the entry.
+func simulateUndo(e journal.Entry, live []api.Record) []api.Record {
+	records := make([]api.Record, 0, len(live)+1)
+	for _, r := range live {
+		if r.RecordId != e.RecordId {
+			records = append(records, r)
+		} else if e.Operation == journal.OpEdit && e.Before != nil {
+			records = append(records, *e.Before)
+		}
+	}
+	if e.Operation == journal.OpDelete && e.Before != nil {
+		records = append(records, *e.Before)
+	}
+	return records
+}
+
+func sameRecordState(a, b *api.Record) bool {
+	return recordKey(a) == recordKey(b) && a.TTL == b.TTL
+}
+
+// applyUndo reverts the entry. A deleted record gets a new ID when added again,
+// newIds maps the old IDs to the new ones for the older entries.
+func applyUndo(e journal.Entry, newIds map[int]int) error {
+	id := e.RecordId
+	if newId, ok := newIds[id]; ok {
+		id = newId
+	}
+	if unknownBefore(e) {
+		return fmt.Errorf("the record before #%d is unknown", e.Seq)
+	}
+	var err error
+	switch e.Operation {
+	case journal.OpAdd:
+		_, err = dnsProvider.Delete(e.Domain, id)
+	case journal.OpDelete:
+		r := copyRecord(e.Before, e.Before.Subdomain)
+		r.AdminMail, r.Refresh, r.Retry, r.Expire, r.NegCache = e.Before.AdminMail, e.Before.Refresh, e.Before.Retry, e.Before.Expire, e.Before.NegCache
+		if _, err = dnsProvider.Create(e.Domain, &r); err == nil {
+			newIds[e.RecordId] = r.RecordId
+		}
+	case journal.OpEdit:
+		r := *e.Before
+		r.RecordId = id
+		_, err = dnsProvider.Update(e.Domain, &r)
+	default:
+		err = fmt.Errorf("unknown operation \"%s\" of #%d", e.Operation, e.Seq)
+	}
+	return err
+}
+
+func init() {
+	RootCmd.AddCommand(undoCmd)
+
+	undoCmd.Flags().IntVarP(&undoLast, "last", "n", 1, "number of the last changes of the domain to revert")
+	undoCmd.Flags().BoolVar(&undoForce, "force", false, "revert even if the records were changed since")
+	undoCmd.Flags().BoolVarP(&undoYes, "yes", "y", false, "do not ask for confirmation")
+	undoCmd.Flags().BoolVar(&undoDryRun, "dry-run", false, "only show the changes")
+}
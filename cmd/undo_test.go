@@ -0,0 +1,214 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/lexty/yandex-dns-cli-manager/journal"
+	"github.com/lexty/yandex-dns-cli-manager/provider"
+)
+
+func entrySeqs(entries []journal.Entry) []int {
+	seqs := make([]int, len(entries))
+	for i, e := range entries {
+		seqs[i] = e.Seq
+	}
+	return seqs
+}
+
+func TestSelectUndoEntries(t *testing.T) {
+	entries := []journal.Entry{
+		{Seq: 1, Domain: "example.com", Operation: journal.OpAdd, RecordId: 1},
+		{Seq: 2, Domain: "example.com", Operation: journal.OpAdd, RecordId: 2},
+		{Seq: 3, Domain: "example.org", Operation: journal.OpAdd, RecordId: 3},
+		{Seq: 4, Domain: "example.com", Operation: journal.OpEdit, RecordId: 1},
+		{Seq: 5, Domain: "example.com", Operation: journal.OpEdit, RecordId: 1, Undoes: 4},
+		{Seq: 6, Domain: "example.com", Operation: journal.OpDelete, RecordId: 2},
+	}
+	defer func(last int) { undoLast = last }(undoLast)
+
+	tests := []struct {
+		name string
+		last int
+		args []string
+		want []int
+	}{
+		{"last change", 1, nil, []int{6}},
+		{"last changes skip undone entries, undos and other domains", 3, nil, []int{6, 2, 1}},
+		{"more than recorded", 10, nil, []int{6, 2, 1}},
+		{"sequence numbers newest first", 1, []string{"1", "3", "2"}, []int{3, 2, 1}},
+		{"undone entry by sequence number", 1, []string{"4"}, []int{4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			undoLast = tt.last
+			if got := entrySeqs(selectUndoEntries(entries, tt.args, "example.com")); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selected %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyUndo(t *testing.T) {
+	defer func(p provider.Provider) { dnsProvider = p }(dnsProvider)
+	dnsProvider = &provider.File{Path: filepath.Join(t.TempDir(), "zones.json")}
+
+	www := api.Record{RecordType: "A", Subdomain: "www", Content: "192.0.2.1", TTL: 3600}
+	mail := api.Record{RecordType: "MX", Subdomain: "@", Content: "mx.example.com.", Priority: "10", TTL: 600}
+	for _, r := range []*api.Record{&www, &mail} {
+		if _, err := dnsProvider.Create("example.com", r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	edited := www
+	edited.Content, edited.TTL = "192.0.2.2", 300
+	if _, err := dnsProvider.Update("example.com", &edited); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dnsProvider.Delete("example.com", mail.RecordId); err != nil {
+		t.Fatal(err)
+	}
+	added := api.Record{RecordType: "TXT", Subdomain: "@", Content: "v=spf1 -all", TTL: 3600}
+	if _, err := dnsProvider.Create("example.com", &added); err != nil {
+		t.Fatal(err)
+	}
+
+	newIds := make(map[int]int)
+	steps := []struct {
+		entry journal.Entry
+		want  string
+	}{
+		{journal.Entry{Seq: 4, Domain: "example.com", Operation: journal.OpAdd, RecordId: added.RecordId, After: &added}, "delete"},
+		{journal.Entry{Seq: 3, Domain: "example.com", Operation: journal.OpDelete, RecordId: mail.RecordId, Before: &mail}, "add again"},
+		{journal.Entry{Seq: 2, Domain: "example.com", Operation: journal.OpEdit, RecordId: www.RecordId, Before: &www, After: &edited}, "restore"},
+	}
+	for _, step := range steps {
+		if got := inverseOperation(step.entry); got != step.want {
+			t.Errorf("inverse of %s = %q, want %q", step.entry.Operation, got, step.want)
+		}
+		list, err := dnsProvider.List("example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if problem := checkUndo(step.entry, list.Records); problem != "" {
+			t.Errorf("checkUndo(#%d) = %q", step.entry.Seq, problem)
+		}
+		if err := applyUndo(step.entry, newIds); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := dnsProvider.List("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]api.Record)
+	for _, r := range list.Records {
+		got[r.RecordType] = r
+	}
+	if len(list.Records) != 2 {
+		t.Errorf("records after undo = %+v", list.Records)
+	}
+	if r := got["A"]; r.RecordId != www.RecordId || r.Content != "192.0.2.1" || r.TTL != 3600 {
+		t.Errorf("restored record = %+v", r)
+	}
+	r := got["MX"]
+	if recordKey(&r) != recordKey(&mail) || r.TTL != 600 {
+		t.Errorf("added again record = %+v", r)
+	}
+	if newIds[mail.RecordId] != r.RecordId || r.RecordId == mail.RecordId {
+		t.Errorf("new IDs = %v, added again record #%d", newIds, r.RecordId)
+	}
+}
+
+func TestUndoUnknownBeforeWithForce(t *testing.T) {
+	defer func(p provider.Provider) { dnsProvider = p }(dnsProvider)
+	dnsProvider = &provider.File{Path: filepath.Join(t.TempDir(), "zones.json")}
+
+	www := api.Record{RecordType: "A", Subdomain: "www", Content: "192.0.2.2", TTL: 3600}
+	if _, err := dnsProvider.Create("example.com", &www); err != nil {
+		t.Fatal(err)
+	}
+	list, err := dnsProvider.List("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the record could not be listed before the change, the journal has no previous state
+	for _, e := range []journal.Entry{
+		{Seq: 2, Domain: "example.com", Operation: journal.OpEdit, RecordId: www.RecordId, After: &www},
+		{Seq: 3, Domain: "example.com", Operation: journal.OpDelete, RecordId: 99},
+	} {
+		steps := []undoStep{{e, checkUndo(e, list.Records)}}
+		if steps[0].problem == "" {
+			t.Errorf("checkUndo(%s) found no problem", e.Operation)
+		}
+		if err := checkUndoSteps(steps, true); err == nil {
+			t.Errorf("checkUndoSteps(%s, force) accepted an entry without the previous state", e.Operation)
+		}
+		if err := applyUndo(e, make(map[int]int)); err == nil {
+			t.Errorf("applyUndo(%s) reverted an entry without the previous state", e.Operation)
+		}
+	}
+
+	if list, _ := dnsProvider.List("example.com"); len(list.Records) != 1 || list.Records[0] != www {
+		t.Errorf("records = %+v", list.Records)
+	}
+}
+
+func TestCheckUndoStepsForce(t *testing.T) {
+	before := api.Record{RecordId: 1, RecordType: "A", Subdomain: "www", Content: "192.0.2.1"}
+	steps := []undoStep{{journal.Entry{Seq: 2, Operation: journal.OpEdit, RecordId: 1, Before: &before}, "record #1 was changed since"}}
+	if err := checkUndoSteps(steps, false); err == nil {
+		t.Error("checkUndoSteps() accepted a changed record without --force")
+	}
+	if err := checkUndoSteps(steps, true); err != nil {
+		t.Errorf("checkUndoSteps(force) = %v", err)
+	}
+}
+
+func TestCheckUndoChangedRecord(t *testing.T) {
+	before := api.Record{RecordId: 1, RecordType: "A", Subdomain: "www", Content: "192.0.2.1", TTL: 3600}
+	after := before
+	after.Content = "192.0.2.2"
+	live := after
+	live.TTL = 300
+
+	tests := []struct {
+		name  string
+		entry journal.Entry
+		live  []api.Record
+	}{
+		{"edited since", journal.Entry{Operation: journal.OpEdit, RecordId: 1, Before: &before, After: &after}, []api.Record{live}},
+		{"deleted since", journal.Entry{Operation: journal.OpAdd, RecordId: 1, After: &after}, nil},
+		{"unknown previous state", journal.Entry{Operation: journal.OpEdit, RecordId: 1, After: &after}, []api.Record{after}},
+		{"added again since", journal.Entry{Operation: journal.OpDelete, RecordId: 1, Before: &before}, []api.Record{before}},
+	}
+	for _, tt := range tests {
+		if problem := checkUndo(tt.entry, tt.live); problem == "" {
+			t.Errorf("%s: checkUndo() found no problem", tt.name)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	eventAdded      = "added"
+	eventRemoved    = "removed"
+	eventChanged    = "changed"
+	eventDriftMiss  = "drift-missing"
+	eventDriftExtra = "drift-unexpected"
+)
+
+var watchInterval time.Duration
+var watchDesiredFile string
+var watchExec string
+var watchEventsFile string
+var watchOnce bool
+
+// watchEvent describes a single change detected between two snapshots of the domain records
+type watchEvent struct {
+	Time     time.Time   `json:"time"`
+	Domain   string      `json:"domain"`
+	Kind     string      `json:"kind"`
+	Record   *api.Record `json:"record,omitempty"`
+	Previous *api.Record `json:"previous,omitempty"`
+}
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watch the DNS records for changes",
+	Long: `Polls the list of the DNS records at an interval and reports added, removed and changed records.
+With --desired the records are also compared with a desired state file (the output of "list --format json")
+and every difference is reported as drift on every poll until it is fixed.
+The errors of a poll, including a failure to write --events, are reported to stderr and the poll is retried
+at the next interval.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if watchInterval <= 0 {
+			throwError(usageError("--interval must be positive."))
+		}
+		checkRequiredSettings()
+		domain := viper.GetString("domain")
+
+		var desired []api.Record
+		if watchDesiredFile != "" {
+			var err error
+			if desired, err = readDesiredState(watchDesiredFile); err != nil {
+				throwError(err)
+			}
+		}
+
+		var prev []api.Record
+		first := true
+		for {
+			list, err := dnsProvider.List(domain)
+			if err != nil {
+				reportWatchError(err)
+				time.Sleep(watchInterval)
+				continue
+			}
+
+			var events []watchEvent
+			now := time.Now()
+			if !first {
+				events = append(events, diffRecords(prev, list.Records, domain, now)...)
+			}
+			if desired != nil {
+				events = append(events, diffDesired(desired, list.Records, domain, now)...)
+			}
+			if first {
+				fmt.Printf("%s  watching %d records of %s\n", now.Format(time.RFC3339), len(list.Records), domain)
+			}
+			if len(events) > 0 {
+				handleWatchEvents(events)
+			}
+
+			prev = list.Records
+			first = false
+			if watchOnce {
+				return
+			}
+			time.Sleep(watchInterval)
+		}
+	},
+}
+
+func readDesiredState(filename string) ([]api.Record, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var state api.Response
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf(`Cannot parse desired state file "%s": %s`, filename, err)
+	}
+	return state.Records, nil
+}
+
+// diffRecords compares two snapshots of the records by record ID.
+func diffRecords(prev, cur []api.Record, domain string, t time.Time) []watchEvent {
+	var events []watchEvent
+	prevById := make(map[int]*api.Record, len(prev))
+	for i := range prev {
+		prevById[prev[i].RecordId] = &prev[i]
+	}
+	curById := make(map[int]*api.Record, len(cur))
+	for i := range cur {
+		r := &cur[i]
+		curById[r.RecordId] = r
+		if p, ok := prevById[r.RecordId]; !ok {
+			events = append(events, watchEvent{Time: t, Domain: domain, Kind: eventAdded, Record: r})
+		} else if recordKey(p) != recordKey(r) || p.TTL != r.TTL {
+			events = append(events, watchEvent{Time: t, Domain: domain, Kind: eventChanged, Record: r, Previous: p})
+		}
+	}
+	for i := range prev {
+		if _, ok := curById[prev[i].RecordId]; !ok {
+			events = append(events, watchEvent{Time: t, Domain: domain, Kind: eventRemoved, Previous: &prev[i]})
+		}
+	}
+	return events
+}
+
+// diffDesired compares the live records with the desired state ignoring record IDs and TTLs.
+func diffDesired(desired, live []api.Record, domain string, t time.Time) []watchEvent {
+	var events []watchEvent
+	liveKeys := make(map[string]bool, len(live))
+	for i := range live {
+		liveKeys[recordKey(&live[i])] = true
+	}
+	desiredKeys := make(map[string]bool, len(desired))
+	for i := range desired {
+		key := recordKey(&desired[i])
+		desiredKeys[key] = true
+		if !liveKeys[key] {
+			events = append(events, watchEvent{Time: t, Domain: domain, Kind: eventDriftMiss, Record: &desired[i]})
+		}
+	}
+	for i := range live {
+		if !desiredKeys[recordKey(&live[i])] {
+			events = append(events, watchEvent{Time: t, Domain: domain, Kind: eventDriftExtra, Record: &live[i]})
+		}
+	}
+	return events
+}
+
+// recordKey identifies the record by its name, type, content and priority.
+// The apex and the trailing dots of the host names are normalized.
+func recordKey(r *api.Record) string {
+	priority := api.PriorityString(r.Priority)
+	subdomain := r.Subdomain
+	if subdomain == "" {
+		subdomain = apexLabel
+	}
+	return strings.Join([]string{subdomain, strings.ToUpper(r.RecordType), strings.TrimSuffix(r.Content, "."), priority}, "\x00")
+}
+
+func formatRecord(r *api.Record) string {
+	s := fmt.Sprintf("#%d %s %s %s ttl=%d", r.RecordId, r.Subdomain, r.RecordType, r.Content, r.TTL)
+	if priority := api.PriorityString(r.Priority); priority != "" {
+		s += " priority=" + priority
+	}
+	return s
+}
+
+// reportWatchError prints the error of a poll to stderr and the watch goes on,
+// only a single poll with --once fails.
+func reportWatchError(err error) {
+	if watchOnce {
+		throwError(err)
+	}
+	fmt.Fprintf(os.Stderr, "%s  Error: %s\n", time.Now().Format(time.RFC3339), err)
+}
+
+func handleWatchEvents(events []watchEvent) {
+	for _, e := range events {
+		switch e.Kind {
+		case eventChanged:
+			fmt.Printf("%s  %-16s %s -> %s\n", e.Time.Format(time.RFC3339), e.Kind, formatRecord(e.Previous), formatRecord(e.Record))
+		case eventRemoved:
+			fmt.Printf("%s  %-16s %s\n", e.Time.Format(time.RFC3339), e.Kind, formatRecord(e.Previous))
+		default:
+			fmt.Printf("%s  %-16s %s\n", e.Time.Format(time.RFC3339), e.Kind, formatRecord(e.Record))
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			reportWatchError(err)
+		}
+	}
+
+	if watchEventsFile != "" {
+		f, err := os.OpenFile(watchEventsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err == nil {
+			_, err = f.Write(buf.Bytes())
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			reportWatchError(fmt.Errorf("cannot write the events to %s: %s", watchEventsFile, err))
+		}
+	}
+
+	if watchExec != "" {
+		c := exec.Command("sh", "-c", watchExec)
+		c.Stdin = &buf
+		c.Stdout = os.Stdout
+		c.Stderr = os.Stderr
+		if err := c.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: command \"%s\" failed: %s\n", watchExec, err)
+		}
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().DurationVarP(&watchInterval, "interval", "i", time.Minute, "polling interval")
+	watchCmd.Flags().StringVarP(&watchDesiredFile, "desired", "D", "", "desired state file to detect drift (output of \"list --format json\")")
+	watchCmd.Flags().StringVarP(&watchExec, "exec", "e", "", "shell command to run when a change is detected (events are passed as JSON lines on stdin)")
+	watchCmd.Flags().StringVarP(&watchEventsFile, "events", "o", "", "file to append change events as JSON lines")
+	watchCmd.Flags().BoolVar(&watchOnce, "once", false, "take a single snapshot, report drift and exit")
+}
@@ -0,0 +1,149 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dnsclient
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+)
+
+const defaultPort = "53"
+
+// Client sends the queries to the DNS servers
+type Client struct {
+	Timeout   time.Duration
+	TCP       bool // always use TCP instead of UDP
+	Recursion bool // ask the server for recursion, needed for resolvers
+}
+
+// Exchange queries the server ("host" or "host:port") for the name and type.
+// A truncated UDP answer is retried over TCP.
+func (c *Client) Exchange(server, name string, qtype uint16) (*Message, error) {
+	id := uint16(rand.Intn(1 << 16))
+	query, err := buildQuery(id, name, qtype, c.Recursion)
+	if err != nil {
+		return nil, err
+	}
+	addr := withPort(server)
+
+	if !c.TCP {
+		m, err := c.exchangeUDP(addr, query)
+		if err != nil || !m.Truncated {
+			return m, checkId(m, id, err)
+		}
+	}
+	m, err := c.exchangeTCP(addr, query)
+	return m, checkId(m, id, err)
+}
+
+func checkId(m *Message, id uint16, err error) error {
+	if err == nil && m.Id != id {
+		return errors.New("dns: answer ID does not match the query")
+	}
+	return err
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return 5 * time.Second
+}
+
+func (c *Client) exchangeUDP(addr string, query []byte) (*Message, error) {
+	conn, err := net.DialTimeout("udp", addr, c.timeout())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.timeout()))
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return parseMessage(buf[:n])
+}
+
+func (c *Client) exchangeTCP(addr string, query []byte) (*Message, error) {
+	conn, err := net.DialTimeout("tcp", addr, c.timeout())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.timeout()))
+	framed := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(framed, uint16(len(query)))
+	copy(framed[2:], query)
+	if _, err := conn.Write(framed); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+	return parseMessage(buf)
+}
+
+// Lookup returns the data of the answer records of the type.
+func (c *Client) Lookup(server, name string, qtype uint16) ([]string, error) {
+	m, err := c.Exchange(server, name, qtype)
+	if err != nil {
+		return nil, err
+	}
+	if m.Rcode != RcodeSuccess && m.Rcode != RcodeNXDomain {
+		return nil, errors.New("dns: server failure, rcode " + rcodeName(m.Rcode))
+	}
+	var data []string
+	for _, rr := range m.Answer {
+		if rr.Type == qtype {
+			data = append(data, rr.Data)
+		}
+	}
+	return data, nil
+}
+
+func rcodeName(rcode int) string {
+	names := map[int]string{1: "FORMERR", 2: "SERVFAIL", 3: "NXDOMAIN", 4: "NOTIMP", 5: "REFUSED"}
+	if name, ok := names[rcode]; ok {
+		return name
+	}
+	return strconv.Itoa(rcode)
+}
+
+func withPort(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(server, defaultPort)
+}
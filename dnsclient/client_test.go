@@ -0,0 +1,216 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dnsclient
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubServer answers the queries on a local UDP and TCP port like a DNS server
+type stubServer struct {
+	addr   string
+	answer func(query []byte, tcp bool) []byte
+
+	mu      sync.Mutex
+	queries []string // "udp" or "tcp" for every query received
+}
+
+func newStubServer(t *testing.T, answer func(query []byte, tcp bool) []byte) *stubServer {
+	s := &stubServer{answer: answer}
+	var udp net.PacketConn
+	var tcp net.Listener
+	// the UDP and TCP listeners must share the port, retry if the port is taken for TCP
+	for attempt := 0; ; attempt++ {
+		var err error
+		if udp, err = net.ListenPacket("udp", "127.0.0.1:0"); err != nil {
+			t.Fatal(err)
+		}
+		if tcp, err = net.Listen("tcp", udp.LocalAddr().String()); err == nil {
+			break
+		}
+		udp.Close()
+		if attempt == 10 {
+			t.Fatal(err)
+		}
+	}
+	s.addr = udp.LocalAddr().String()
+	t.Cleanup(func() {
+		udp.Close()
+		tcp.Close()
+	})
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			s.record("udp")
+			udp.WriteTo(s.answer(buf[:n], false), from)
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+			s.record("tcp")
+			var length [2]byte
+			if _, err := io.ReadFull(conn, length[:]); err == nil {
+				query := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, query); err == nil {
+					resp := s.answer(query, true)
+					conn.Write(append(u16(uint16(len(resp))), resp...))
+				}
+			}
+			conn.Close()
+		}
+	}()
+	return s
+}
+
+func (s *stubServer) record(network string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries = append(s.queries, network)
+}
+
+func (s *stubServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queries...)
+}
+
+func queryId(query []byte) uint16 {
+	return binary.BigEndian.Uint16(query)
+}
+
+var testA = testRR{pointer(questionOffset), TypeA, []byte{192, 0, 2, 1}}
+
+func testClient() *Client {
+	return &Client{Timeout: 2 * time.Second}
+}
+
+func TestExchangeUDP(t *testing.T) {
+	s := newStubServer(t, func(query []byte, tcp bool) []byte {
+		return buildResponse(queryId(query), 0x8400, testA)
+	})
+	data, err := testClient().Lookup(s.addr, "example.com", TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(data, []string{"192.0.2.1"}) {
+		t.Errorf("Lookup() = %v", data)
+	}
+	if got := s.received(); !reflect.DeepEqual(got, []string{"udp"}) {
+		t.Errorf("queries = %v", got)
+	}
+}
+
+func TestExchangeTruncatedRetriesTCP(t *testing.T) {
+	s := newStubServer(t, func(query []byte, tcp bool) []byte {
+		if !tcp {
+			return buildResponse(queryId(query), 0x8600) // TC without answers
+		}
+		return buildResponse(queryId(query), 0x8400, testA, testRR{pointer(questionOffset), TypeA, []byte{192, 0, 2, 2}})
+	})
+	m, err := testClient().Exchange(s.addr, "example.com", TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Truncated || len(m.Answer) != 2 {
+		t.Errorf("message = %+v", m)
+	}
+	if got := s.received(); !reflect.DeepEqual(got, []string{"udp", "tcp"}) {
+		t.Errorf("queries = %v", got)
+	}
+}
+
+func TestExchangeTCPOnly(t *testing.T) {
+	s := newStubServer(t, func(query []byte, tcp bool) []byte {
+		return buildResponse(queryId(query), 0x8400, testA)
+	})
+	c := testClient()
+	c.TCP = true
+	if _, err := c.Exchange(s.addr, "example.com", TypeA); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.received(); !reflect.DeepEqual(got, []string{"tcp"}) {
+		t.Errorf("queries = %v", got)
+	}
+}
+
+func TestExchangeIdMismatch(t *testing.T) {
+	s := newStubServer(t, func(query []byte, tcp bool) []byte {
+		return buildResponse(queryId(query)+1, 0x8400, testA)
+	})
+	if _, err := testClient().Exchange(s.addr, "example.com", TypeA); err == nil {
+		t.Error("Exchange() accepted an answer to another query")
+	}
+}
+
+func TestLookupRcodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   uint16
+		answers []testRR
+		want    []string
+		wantErr bool
+	}{
+		{"other types are skipped", 0x8400, []testRR{{pointer(questionOffset), TypeCNAME, wireName("example.net.")}, testA}, []string{"192.0.2.1"}, false},
+		{"NXDOMAIN has no data", 0x8403, nil, nil, false},
+		{"SERVFAIL", 0x8402, nil, nil, true},
+		{"REFUSED", 0x8405, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStubServer(t, func(query []byte, tcp bool) []byte {
+				return buildResponse(queryId(query), tt.flags, tt.answers...)
+			})
+			data, err := testClient().Lookup(s.addr, "example.com", TypeA)
+			if (err != nil) != tt.wantErr || !reflect.DeepEqual(data, tt.want) {
+				t.Errorf("Lookup() = %v, %v", data, err)
+			}
+		})
+	}
+}
+
+func TestWithPort(t *testing.T) {
+	for server, want := range map[string]string{
+		"192.0.2.53":       "192.0.2.53:53",
+		"192.0.2.53:5353":  "192.0.2.53:5353",
+		"ns1.example.com":  "ns1.example.com:53",
+		"2001:db8::53":     "[2001:db8::53]:53",
+		"[2001:db8::53]:5": "[2001:db8::53]:5",
+	} {
+		if got := withPort(server); got != want {
+			t.Errorf("withPort(%q) = %q, want %q", server, got, want)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package dnsclient implements a minimal DNS client for querying the authoritative servers directly.
+package dnsclient
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Record types
+const (
+	TypeA     uint16 = 1
+	TypeNS    uint16 = 2
+	TypeCNAME uint16 = 5
+	TypeSOA   uint16 = 6
+	TypeMX    uint16 = 15
+	TypeTXT   uint16 = 16
+	TypeAAAA  uint16 = 28
+	TypeSRV   uint16 = 33
+
+	classIN uint16 = 1
+)
+
+// Response codes
+const (
+	RcodeSuccess  = 0
+	RcodeNXDomain = 3
+)
+
+var typeNames = map[uint16]string{
+	TypeA: "A", TypeNS: "NS", TypeCNAME: "CNAME", TypeSOA: "SOA",
+	TypeMX: "MX", TypeTXT: "TXT", TypeAAAA: "AAAA", TypeSRV: "SRV",
+}
+
+var errShortMessage = errors.New("dns: message is too short")
+
+// TypeByName returns the record type by its name, e.g. "MX".
+func TypeByName(name string) (uint16, bool) {
+	for t, n := range typeNames {
+		if n == strings.ToUpper(name) {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// TypeName returns the name of the record type.
+func TypeName(t uint16) string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", t)
+}
+
+// RR is a resource record of the answer
+type RR struct {
+	Name string
+	Type uint16
+	TTL  uint32
+	Data string // presentation form of the data, e.g. "10 mx.example.com." for MX
+}
+
+// Message is the parsed DNS response
+type Message struct {
+	Id            uint16
+	Authoritative bool
+	Truncated     bool
+	Rcode         int
+	Answer        []RR
+	Authority     []RR
+}
+
+// buildQuery encodes the query for the name and type.
+func buildQuery(id uint16, name string, qtype uint16, recursion bool) ([]byte, error) {
+	msg := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	if recursion {
+		msg[2] = 0x01 // RD
+	}
+	binary.BigEndian.PutUint16(msg[4:], 1) // QDCOUNT
+	var err error
+	if msg, err = appendName(msg, name); err != nil {
+		return nil, err
+	}
+	msg = append(msg, 0, 0, 0, 0)
+	binary.BigEndian.PutUint16(msg[len(msg)-4:], qtype)
+	binary.BigEndian.PutUint16(msg[len(msg)-2:], classIN)
+	return msg, nil
+}
+
+func appendName(msg []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if len(label) == 0 || len(label) > 63 {
+				return nil, fmt.Errorf("dns: invalid name %q", name)
+			}
+			msg = append(msg, byte(len(label)))
+			msg = append(msg, label...)
+		}
+	}
+	return append(msg, 0), nil
+}
+
+// parseMessage decodes the response.
+func parseMessage(msg []byte) (*Message, error) {
+	if len(msg) < 12 {
+		return nil, errShortMessage
+	}
+	m := &Message{
+		Id:            binary.BigEndian.Uint16(msg[0:]),
+		Authoritative: msg[2]&0x04 != 0,
+		Truncated:     msg[2]&0x02 != 0,
+		Rcode:         int(msg[3] & 0x0f),
+	}
+	qdcount := int(binary.BigEndian.Uint16(msg[4:]))
+	ancount := int(binary.BigEndian.Uint16(msg[6:]))
+	nscount := int(binary.BigEndian.Uint16(msg[8:]))
+
+	off := 12
+	for i := 0; i < qdcount; i++ {
+		var err error
+		if _, off, err = readName(msg, off); err != nil {
+			return nil, err
+		}
+		off += 4
+	}
+	var err error
+	if m.Answer, off, err = readRRs(msg, off, ancount); err != nil {
+		return nil, err
+	}
+	if m.Authority, _, err = readRRs(msg, off, nscount); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func readRRs(msg []byte, off, count int) ([]RR, int, error) {
+	var rrs []RR
+	for i := 0; i < count; i++ {
+		name, next, err := readName(msg, off)
+		if err != nil {
+			return nil, off, err
+		}
+		if next+10 > len(msg) {
+			return nil, off, errShortMessage
+		}
+		rr := RR{
+			Name: name,
+			Type: binary.BigEndian.Uint16(msg[next:]),
+			TTL:  binary.BigEndian.Uint32(msg[next+4:]),
+		}
+		length := int(binary.BigEndian.Uint16(msg[next+8:]))
+		start := next + 10
+		if start+length > len(msg) {
+			return nil, off, errShortMessage
+		}
+		if rr.Data, err = readData(msg, start, length, rr.Type); err != nil {
+			return nil, off, err
+		}
+		rrs = append(rrs, rr)
+		off = start + length
+	}
+	return rrs, off, nil
+}
+
+// readName decodes the possibly compressed name and returns the offset after it.
+func readName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, errShortMessage
+		}
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, ".") + ".", next, nil
+		case length&0xc0 == 0xc0:
+			if off+1 >= len(msg) {
+				return "", 0, errShortMessage
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			if jumps++; jumps > 32 {
+				return "", 0, errors.New("dns: too many compression pointers")
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+		default:
+			if off+1+length > len(msg) {
+				return "", 0, errShortMessage
+			}
+			labels = append(labels, string(msg[off+1:off+1+length]))
+			off += 1 + length
+		}
+	}
+}
+
+func readData(msg []byte, off, length int, t uint16) (string, error) {
+	data := msg[off : off+length]
+	switch t {
+	case TypeA:
+		if length != net.IPv4len {
+			return "", errShortMessage
+		}
+		return net.IP(data).String(), nil
+	case TypeAAAA:
+		if length != net.IPv6len {
+			return "", errShortMessage
+		}
+		return net.IP(data).String(), nil
+	case TypeNS, TypeCNAME:
+		name, _, err := readName(msg, off)
+		return name, err
+	case TypeMX:
+		if length < 3 {
+			return "", errShortMessage
+		}
+		name, _, err := readName(msg, off+2)
+		return fmt.Sprintf("%d %s", binary.BigEndian.Uint16(data), name), err
+	case TypeSRV:
+		if length < 7 {
+			return "", errShortMessage
+		}
+		name, _, err := readName(msg, off+6)
+		return fmt.Sprintf("%d %d %d %s", binary.BigEndian.Uint16(data), binary.BigEndian.Uint16(data[2:]),
+			binary.BigEndian.Uint16(data[4:]), name), err
+	case TypeTXT:
+		var parts []string
+		for i := 0; i < len(data); {
+			l := int(data[i])
+			if i+1+l > len(data) {
+				return "", errShortMessage
+			}
+			parts = append(parts, string(data[i+1:i+1+l]))
+			i += 1 + l
+		}
+		return strings.Join(parts, ""), nil
+	case TypeSOA:
+		mname, next, err := readName(msg, off)
+		if err != nil {
+			return "", err
+		}
+		rname, next, err := readName(msg, next)
+		if err != nil {
+			return "", err
+		}
+		if next+20 > off+length {
+			return "", errShortMessage
+		}
+		v := make([]uint32, 5)
+		for i := range v {
+			v[i] = binary.BigEndian.Uint32(msg[next+4*i:])
+		}
+		return fmt.Sprintf("%s %s %d %d %d %d %d", mname, rname, v[0], v[1], v[2], v[3], v[4]), nil
+	}
+	return fmt.Sprintf("\\# %d %x", length, data), nil
+}
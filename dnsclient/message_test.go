@@ -0,0 +1,185 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dnsclient
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testRR is a record of a crafted response, the name and the data are raw wire bytes
+type testRR struct {
+	name  []byte
+	rtype uint16
+	data  []byte
+}
+
+// wireName encodes the name without compression.
+func wireName(name string) []byte {
+	b, err := appendName(nil, name)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// pointer encodes a compression pointer to the offset.
+func pointer(off int) []byte {
+	return []byte{0xc0 | byte(off>>8), byte(off)}
+}
+
+func u16(v uint16) []byte {
+	return []byte{byte(v >> 8), byte(v)}
+}
+
+func u32(v uint32) []byte {
+	return []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+}
+
+func concat(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+// questionOffset is the offset of the question name in the responses built by buildResponse,
+// the answers can point to it.
+const questionOffset = 12
+
+// buildResponse encodes the response to the query of "example.com." with the answer records.
+func buildResponse(id uint16, flags uint16, answers ...testRR) []byte {
+	msg := concat(u16(id), u16(flags), u16(1), u16(uint16(len(answers))), u16(0), u16(0))
+	msg = append(msg, wireName("example.com.")...)
+	msg = append(msg, concat(u16(TypeA), u16(classIN))...)
+	for _, rr := range answers {
+		msg = append(msg, rr.name...)
+		msg = append(msg, concat(u16(rr.rtype), u16(classIN), u32(300), u16(uint16(len(rr.data))))...)
+		msg = append(msg, rr.data...)
+	}
+	return msg
+}
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		rr   testRR
+		want RR
+	}{
+		{"A", testRR{pointer(questionOffset), TypeA, []byte{192, 0, 2, 1}},
+			RR{"example.com.", TypeA, 300, "192.0.2.1"}},
+		{"AAAA", testRR{wireName("www.example.com."), TypeAAAA, []byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}},
+			RR{"www.example.com.", TypeAAAA, 300, "2001:db8::1"}},
+		{"NS with a compressed name", testRR{pointer(questionOffset), TypeNS, concat([]byte{3}, []byte("ns1"), pointer(questionOffset))},
+			RR{"example.com.", TypeNS, 300, "ns1.example.com."}},
+		{"CNAME", testRR{concat([]byte{3}, []byte("www"), pointer(questionOffset)), TypeCNAME, wireName("example.net.")},
+			RR{"www.example.com.", TypeCNAME, 300, "example.net."}},
+		{"MX", testRR{pointer(questionOffset), TypeMX, concat(u16(10), []byte{2}, []byte("mx"), pointer(questionOffset))},
+			RR{"example.com.", TypeMX, 300, "10 mx.example.com."}},
+		{"SRV", testRR{wireName("_sip._tcp.example.com."), TypeSRV, concat(u16(10), u16(5), u16(5060), wireName("sip.example.com."))},
+			RR{"_sip._tcp.example.com.", TypeSRV, 300, "10 5 5060 sip.example.com."}},
+		{"TXT of several strings", testRR{pointer(questionOffset), TypeTXT, concat([]byte{6}, []byte("v=spf1"), []byte{5}, []byte(" -all"), []byte{0})},
+			RR{"example.com.", TypeTXT, 300, "v=spf1 -all"}},
+		{"SOA", testRR{pointer(questionOffset), TypeSOA, concat(wireName("ns1.example.com."), wireName("hostmaster.example.com."),
+			u32(2016010101), u32(14400), u32(900), u32(1209600), u32(3600))},
+			RR{"example.com.", TypeSOA, 300, "ns1.example.com. hostmaster.example.com. 2016010101 14400 900 1209600 3600"}},
+		{"unknown type", testRR{pointer(questionOffset), 99, []byte{0xab, 0xcd}},
+			RR{"example.com.", 99, 300, `\# 2 abcd`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := parseMessage(buildResponse(0x1234, 0x8400, tt.rr))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Id != 0x1234 || !m.Authoritative || m.Truncated || m.Rcode != RcodeSuccess {
+				t.Errorf("header = %+v", m)
+			}
+			if len(m.Answer) != 1 || !reflect.DeepEqual(m.Answer[0], tt.want) {
+				t.Errorf("answer = %+v, want %+v", m.Answer, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMessageHeader(t *testing.T) {
+	m, err := parseMessage(buildResponse(1, 0x8303))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Authoritative || !m.Truncated || m.Rcode != RcodeNXDomain || len(m.Answer) != 0 {
+		t.Errorf("message = %+v", m)
+	}
+}
+
+func TestParseMessageMalformed(t *testing.T) {
+	valid := buildResponse(1, 0x8400, testRR{pointer(questionOffset), TypeA, []byte{192, 0, 2, 1}})
+	// the answer of the valid response starts after the header and the question
+	answerOffset := questionOffset + len(wireName("example.com.")) + 4
+
+	withAnswerName := func(name []byte) []byte {
+		msg := append([]byte(nil), valid[:answerOffset]...)
+		return append(append(msg, name...), valid[answerOffset+2:]...)
+	}
+	tests := []struct {
+		name string
+		msg  []byte
+	}{
+		{"short header", valid[:11]},
+		{"truncated question", valid[:questionOffset+5]},
+		{"truncated answer header", valid[:answerOffset+6]},
+		{"truncated RDATA", valid[:len(valid)-1]},
+		{"pointer loop", withAnswerName(pointer(answerOffset))},
+		{"pointer out of range", withAnswerName(pointer(0x3fff))},
+		{"pointer cut in half", valid[:answerOffset+1]},
+		{"A of 3 bytes", buildResponse(1, 0x8400, testRR{pointer(questionOffset), TypeA, []byte{192, 0, 2}})},
+		{"AAAA of 4 bytes", buildResponse(1, 0x8400, testRR{pointer(questionOffset), TypeAAAA, []byte{192, 0, 2, 1}})},
+		{"MX without a name", buildResponse(1, 0x8400, testRR{pointer(questionOffset), TypeMX, u16(10)})},
+		{"SRV without a name", buildResponse(1, 0x8400, testRR{pointer(questionOffset), TypeSRV, concat(u16(10), u16(5), u16(5060))})},
+		{"TXT string longer than the data", buildResponse(1, 0x8400, testRR{pointer(questionOffset), TypeTXT, concat([]byte{10}, []byte("v=spf1"))})},
+		{"SOA without the timers", buildResponse(1, 0x8400, testRR{pointer(questionOffset), TypeSOA,
+			concat(wireName("ns1.example.com."), wireName("hostmaster.example.com."), u32(1), u32(2))})},
+		{"name label past the end", withAnswerName([]byte{40, 'x'})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if m, err := parseMessage(tt.msg); err == nil {
+				t.Errorf("parseMessage() = %+v, want an error", m)
+			}
+		})
+	}
+}
+
+func TestBuildQuery(t *testing.T) {
+	query, err := buildQuery(0xbeef, "www.example.com", TypeMX, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := concat(u16(0xbeef), u16(0x0100), u16(1), u16(0), u16(0), u16(0), wireName("www.example.com."), u16(TypeMX), u16(classIN))
+	if !reflect.DeepEqual(query, want) {
+		t.Errorf("query = %x, want %x", query, want)
+	}
+
+	if _, err := buildQuery(1, "www..example.com", TypeA, false); err == nil {
+		t.Error("buildQuery() accepted an empty label")
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package journal implements a local append-only journal of the record changes.
+// Every entry keeps the hash of the previous one, so removed or altered entries break the chain.
+package journal
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+)
+
+// Operations
+const (
+	OpAdd    = "add"
+	OpEdit   = "edit"
+	OpDelete = "delete"
+)
+
+// Entry is a change of a record
+type Entry struct {
+	Seq       int         `json:"seq"`
+	Time      time.Time   `json:"time"`
+	User      string      `json:"user"`
+	Profile   string      `json:"profile,omitempty"`
+	Domain    string      `json:"domain"`
+	Operation string      `json:"operation"`
+	RecordId  int         `json:"record_id"`
+	Before    *api.Record `json:"before,omitempty"`
+	After     *api.Record `json:"after,omitempty"`
+	Response  string      `json:"response,omitempty"`
+	Undoes    int         `json:"undoes,omitempty"` // sequence number of the undone entry
+	PrevHash  string      `json:"prev_hash"`
+	Hash      string      `json:"hash"`
+}
+
+// Journal is a file of the entries, one JSON object per line
+type Journal struct {
+	Path string
+}
+
+// Open returns the journal kept in the file, the file is created by the first Append.
+func Open(path string) *Journal {
+	return &Journal{Path: path}
+}
+
+// computeHash returns the hash of the entry chained to the previous entry.
+func computeHash(e Entry) (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append([]byte(e.PrevHash+"\n"), data...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Append sets the sequence number and the hashes of the entry and writes it to the end of the journal.
+// The journal file is locked while the last entry is read and the new one is written,
+// so the commands and daemons appending at the same time keep a single chain.
+func (j *Journal) Append(e *Entry) (err error) {
+	f, err := os.OpenFile(j.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	if err := lockFile(f); err != nil {
+		return err
+	}
+	defer unlockFile(f)
+
+	entries, err := j.Entries()
+	if err != nil {
+		return err
+	}
+	e.Seq, e.PrevHash = 1, ""
+	if len(entries) > 0 {
+		last := entries[len(entries)-1]
+		e.Seq, e.PrevHash = last.Seq+1, last.Hash
+	}
+	if e.Hash, err = computeHash(*e); err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Entries reads all entries of the journal, a missing journal has no entries.
+func (j *Journal) Entries() ([]Entry, error) {
+	f, err := os.Open(j.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("journal %s, line %d: %s", j.Path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Verify checks the hash chain of the entries and returns an error describing the first broken entry.
+func Verify(entries []Entry) error {
+	prev := ""
+	for i, e := range entries {
+		if e.Seq != i+1 {
+			return fmt.Errorf("entry %d has the sequence number %d, entries are missing or reordered", i+1, e.Seq)
+		}
+		if e.PrevHash != prev {
+			return fmt.Errorf("entry %d does not follow the previous entry", e.Seq)
+		}
+		hash, err := computeHash(e)
+		if err != nil {
+			return err
+		}
+		if hash != e.Hash {
+			return fmt.Errorf("entry %d was altered", e.Seq)
+		}
+		prev = e.Hash
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+)
+
+func newTestJournal(t *testing.T) *Journal {
+	return Open(filepath.Join(t.TempDir(), "journal.jsonl"))
+}
+
+// appendEntries appends an add, an edit and a delete of one record.
+func appendEntries(t *testing.T, j *Journal) []Entry {
+	r := api.Record{RecordId: 1, RecordType: "A", Subdomain: "www", Content: "192.0.2.1", TTL: 3600}
+	changed := r
+	changed.Content = "192.0.2.2"
+	for _, e := range []Entry{
+		{Domain: "example.com", Operation: OpAdd, RecordId: 1, After: &r},
+		{Domain: "example.com", Operation: OpEdit, RecordId: 1, Before: &r, After: &changed},
+		{Domain: "example.com", Operation: OpDelete, RecordId: 1, Before: &changed},
+	} {
+		if err := j.Append(&e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := j.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestAppendChainsEntries(t *testing.T) {
+	j := newTestJournal(t)
+	if _, err := os.Stat(j.Path); !os.IsNotExist(err) {
+		t.Fatalf("journal exists before the first Append: %v", err)
+	}
+
+	entries := appendEntries(t, j)
+	if len(entries) != 3 {
+		t.Fatalf("entries = %+v", entries)
+	}
+	prev := ""
+	for i, e := range entries {
+		if e.Seq != i+1 {
+			t.Errorf("entry %d has the sequence number %d", i+1, e.Seq)
+		}
+		if e.PrevHash != prev {
+			t.Errorf("entry %d prev_hash = %q, want %q", e.Seq, e.PrevHash, prev)
+		}
+		if e.Hash == "" || e.Hash == e.PrevHash {
+			t.Errorf("entry %d hash = %q", e.Seq, e.Hash)
+		}
+		prev = e.Hash
+	}
+	if entries[1].Before.Content != "192.0.2.1" || entries[1].After.Content != "192.0.2.2" {
+		t.Errorf("edit entry = %+v", entries[1])
+	}
+	if err := Verify(entries); err != nil {
+		t.Error(err)
+	}
+
+	if info, err := os.Stat(j.Path); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("journal mode = %v", info.Mode().Perm())
+	}
+}
+
+func TestAppendConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// every writer opens the journal on its own like a separate command
+			if err := Open(path).Append(&Entry{Domain: "example.com", Operation: OpAdd}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, err := Open(path).Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 50 {
+		t.Errorf("%d entries, want 50", len(entries))
+	}
+	if err := Verify(entries); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func([]Entry) []Entry
+		want   string
+	}{
+		{"intact", func(e []Entry) []Entry { return e }, ""},
+		{"edited entry", func(e []Entry) []Entry {
+			after := *e[1].After
+			after.Content = "203.0.113.1"
+			e[1].After = &after
+			return e
+		}, "entry 2 was altered"},
+		{"edited entry with a recomputed hash", func(e []Entry) []Entry {
+			e[1].User = "mallory"
+			e[1].Hash, _ = computeHash(e[1])
+			return e
+		}, "entry 3 does not follow"},
+		{"deleted middle entry", func(e []Entry) []Entry {
+			return []Entry{e[0], e[2]}
+		}, "sequence number 3"},
+		{"reordered entries", func(e []Entry) []Entry {
+			return []Entry{e[0], e[2], e[1]}
+		}, "sequence number 3"},
+		{"deleted first entry", func(e []Entry) []Entry {
+			return e[1:]
+		}, "sequence number 2"},
+		{"renumbered entries after a deleted first entry", func(e []Entry) []Entry {
+			e = e[1:]
+			e[0].Seq, e[1].Seq = 1, 2
+			return e
+		}, "entry 1 does not follow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.change(appendEntries(t, newTestJournal(t))))
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("Verify() = %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("Verify() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntriesMissingJournal(t *testing.T) {
+	entries, err := newTestJournal(t).Entries()
+	if err != nil || entries != nil {
+		t.Errorf("Entries() = %+v, %v", entries, err)
+	}
+}
+
+func TestEntriesMalformedLine(t *testing.T) {
+	j := newTestJournal(t)
+	appendEntries(t, j)
+	data, err := os.ReadFile(j.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a journal cut in the middle of the first entry
+	if err := os.WriteFile(j.Path, data[10:], 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = j.Entries()
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Entries() = %v, want an error of line 1", err)
+	}
+}
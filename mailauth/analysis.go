@@ -0,0 +1,105 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package mailauth builds and analyzes the SPF, DKIM and DMARC records.
+package mailauth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Problem severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Problem is an error or a weakness found in a record
+type Problem struct {
+	Severity string
+	Message  string
+}
+
+// Term is a part of a record with its meaning
+type Term struct {
+	Text        string
+	Explanation string
+}
+
+// Analysis is the result of parsing a record
+type Analysis struct {
+	Terms    []Term
+	Problems []Problem
+}
+
+// HasErrors reports whether the record is invalid.
+func (a *Analysis) HasErrors() bool {
+	for _, p := range a.Problems {
+		if p.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Analysis) explain(text, format string, args ...interface{}) {
+	a.Terms = append(a.Terms, Term{text, fmt.Sprintf(format, args...)})
+}
+
+func (a *Analysis) errorf(format string, args ...interface{}) {
+	a.Problems = append(a.Problems, Problem{SeverityError, fmt.Sprintf(format, args...)})
+}
+
+func (a *Analysis) warnf(format string, args ...interface{}) {
+	a.Problems = append(a.Problems, Problem{SeverityWarning, fmt.Sprintf(format, args...)})
+}
+
+// tag is a "name=value" pair of a DKIM or DMARC record
+type tag struct {
+	Text  string
+	Name  string
+	Value string
+}
+
+// parseTags splits a tag list ("v=DMARC1; p=none") and reports the malformed and duplicate tags.
+func parseTags(record string, a *Analysis) []tag {
+	var tags []tag
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(record, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		eq := strings.Index(part, "=")
+		if eq < 1 {
+			a.errorf(`malformed tag "%s", expected name=value`, part)
+			continue
+		}
+		t := tag{part, strings.ToLower(strings.TrimSpace(part[:eq])), strings.TrimSpace(part[eq+1:])}
+		if seen[t.Name] {
+			a.errorf(`duplicate tag "%s"`, t.Name)
+			continue
+		}
+		seen[t.Name] = true
+		tags = append(tags, t)
+	}
+	return tags
+}
@@ -0,0 +1,62 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mailauth
+
+import (
+	"strings"
+	"testing"
+)
+
+// problemCase is a record with the expected problems, each given by a part of its message
+type problemCase struct {
+	record   string
+	errors   []string
+	warnings []string
+}
+
+func checkProblems(t *testing.T, record string, problems []Problem, errors, warnings []string) {
+	t.Helper()
+	want := map[string][]string{SeverityError: errors, SeverityWarning: warnings}
+	got := make(map[string][]string)
+	for _, p := range problems {
+		got[p.Severity] = append(got[p.Severity], p.Message)
+	}
+	for _, severity := range []string{SeverityError, SeverityWarning} {
+		if len(got[severity]) != len(want[severity]) {
+			t.Errorf("%q: %ss %q, want %q", record, severity, got[severity], want[severity])
+			continue
+		}
+		for i, part := range want[severity] {
+			if !strings.Contains(got[severity][i], part) {
+				t.Errorf("%q: %s %q does not contain %q", record, severity, got[severity][i], part)
+			}
+		}
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	var a Analysis
+	tags := parseTags(" v=DMARC1 ;P = reject; ; broken; p=none", &a)
+	if len(tags) != 2 || tags[1].Name != "p" || tags[1].Value != "reject" {
+		t.Errorf("tags = %+v", tags)
+	}
+	checkProblems(t, "tags", a.Problems, []string{`malformed tag "broken"`, `duplicate tag "p"`}, nil)
+}
@@ -0,0 +1,190 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mailauth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"strings"
+)
+
+// DKIM key types
+const (
+	KeyTypeRSA     = "rsa"
+	KeyTypeEd25519 = "ed25519"
+)
+
+// DKIM is the structured form of a DKIM key record
+type DKIM struct {
+	KeyType   string
+	PublicKey string // base64 encoded public key
+	Hashes    []string
+	Testing   bool
+}
+
+// String returns the text of the record.
+func (d DKIM) String() string {
+	tags := []string{"v=DKIM1"}
+	if d.KeyType != "" {
+		tags = append(tags, "k="+d.KeyType)
+	}
+	if len(d.Hashes) > 0 {
+		tags = append(tags, "h="+strings.Join(d.Hashes, ":"))
+	}
+	if d.Testing {
+		tags = append(tags, "t=y")
+	}
+	tags = append(tags, "p="+d.PublicKey)
+	return strings.Join(tags, "; ")
+}
+
+// IsDKIM reports whether the TXT record is a DKIM key record.
+func IsDKIM(txt string) bool {
+	txt = strings.ToLower(strings.Replace(txt, " ", "", -1))
+	return strings.HasPrefix(txt, "v=dkim1") || strings.Contains(txt, "p=")
+}
+
+// DKIMPublicKey converts a PEM encoded public key to the key type and the value of the "p" tag.
+func DKIMPublicKey(data []byte) (keyType, publicKey string, err error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", "", errors.New("no PEM encoded public key found")
+	}
+	var key interface{}
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return "", "", errors.New(`expected a "PUBLIC KEY" PEM block, got "` + block.Type + `"`)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		der, err := x509.MarshalPKIXPublicKey(k)
+		if err != nil {
+			return "", "", err
+		}
+		return KeyTypeRSA, base64.StdEncoding.EncodeToString(der), nil
+	case ed25519.PublicKey:
+		return KeyTypeEd25519, base64.StdEncoding.EncodeToString(k), nil
+	}
+	return "", "", errors.New("only RSA and Ed25519 keys are supported")
+}
+
+// AnalyzeDKIM parses the DKIM key record and explains its tags.
+func AnalyzeDKIM(record string) Analysis {
+	var a Analysis
+	tags := parseTags(record, &a)
+	keyType, publicKey, hasKey := KeyTypeRSA, "", false
+	for i, t := range tags {
+		switch t.Name {
+		case "v":
+			a.explain(t.Text, "DKIM key record version 1")
+			if t.Value != "DKIM1" {
+				a.errorf(`the version must be "DKIM1"`)
+			}
+			if i != 0 {
+				a.errorf(`the "v" tag must be the first one`)
+			}
+		case "k":
+			keyType = strings.ToLower(t.Value)
+			a.explain(t.Text, "key type %s", keyType)
+			if keyType != KeyTypeRSA && keyType != KeyTypeEd25519 {
+				a.errorf(`unknown key type "%s"`, t.Value)
+			}
+		case "p":
+			publicKey, hasKey = strings.Replace(t.Value, " ", "", -1), true
+			text := t.Text
+			if len(text) > 24 {
+				text = text[:24] + "..."
+			}
+			a.explain(text, "public key")
+		case "h":
+			a.explain(t.Text, "allowed hash algorithms")
+			for _, h := range strings.Split(t.Value, ":") {
+				if strings.TrimSpace(h) == "sha1" {
+					a.warnf("sha1 is insecure, allow sha256 only")
+				}
+			}
+		case "t":
+			a.explain(t.Text, "flags: y - testing mode, s - no subdomains")
+			if strings.Contains(t.Value, "y") {
+				a.warnf("the key is in testing mode, the receivers treat signed and unsigned messages alike")
+			}
+		case "s":
+			a.explain(t.Text, "service types")
+		case "n":
+			a.explain(t.Text, "notes")
+		default:
+			a.explain(t.Text, "unknown tag, ignored")
+		}
+	}
+
+	if !hasKey {
+		a.errorf(`the "p" tag with the public key is required`)
+		return a
+	}
+	if publicKey == "" {
+		a.warnf("the key is revoked (empty \"p\" tag)")
+		return a
+	}
+	der, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		a.errorf("the public key is not valid base64: %s", err)
+		return a
+	}
+	switch keyType {
+	case KeyTypeRSA:
+		key, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			if key, err = x509.ParsePKCS1PublicKey(der); err != nil {
+				a.errorf("the public key is not a valid RSA key")
+				return a
+			}
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			a.errorf("the public key is not an RSA key")
+			return a
+		}
+		bits := rsaKey.N.BitLen()
+		a.explain("", "RSA key of %d bits", bits)
+		if bits < 1024 {
+			a.errorf("the %d-bit RSA key is too short, receivers ignore keys shorter than 1024 bits", bits)
+		} else if bits < 2048 {
+			a.warnf("the %d-bit RSA key is weak, use 2048 bits", bits)
+		}
+	case KeyTypeEd25519:
+		if len(der) != ed25519.PublicKeySize {
+			a.errorf("the Ed25519 public key must be %d bytes", ed25519.PublicKeySize)
+		}
+	}
+	return a
+}
@@ -0,0 +1,104 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mailauth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func rsaPEM(t *testing.T, bits int) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestDKIMPublicKey(t *testing.T) {
+	keyType, p, err := DKIMPublicKey(rsaPEM(t, 2048))
+	if err != nil || keyType != KeyTypeRSA || p == "" {
+		t.Errorf("DKIMPublicKey(RSA) = %q, %q, %v", keyType, p, err)
+	}
+
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(pub)
+	keyType, p, err = DKIMPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil || keyType != KeyTypeEd25519 || len(p) != 44 {
+		t.Errorf("DKIMPublicKey(Ed25519) = %q, %q, %v", keyType, p, err)
+	}
+
+	if _, _, err := DKIMPublicKey([]byte("not a key")); err == nil {
+		t.Error("DKIMPublicKey accepted a non-PEM input")
+	}
+	if _, _, err := DKIMPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})); err == nil {
+		t.Error("DKIMPublicKey accepted a private key block")
+	}
+}
+
+func TestAnalyzeDKIM(t *testing.T) {
+	_, strong, _ := DKIMPublicKey(rsaPEM(t, 2048))
+	_, weak, _ := DKIMPublicKey(rsaPEM(t, 1024))
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKIXPublicKey(pub)
+	_, ed, _ := DKIMPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	tests := []problemCase{
+		{record: "v=DKIM1; k=rsa; p=" + strong},
+		{record: "v=DKIM1; k=ed25519; p=" + ed},
+		{record: DKIM{KeyType: KeyTypeRSA, PublicKey: strong}.String()},
+		{record: "v=DKIM1; p=" + weak, warnings: []string{"1024-bit RSA key is weak"}},
+		{record: "v=DKIM1; p=", warnings: []string{"revoked"}},
+		{record: "v=DKIM1; k=rsa", errors: []string{`"p" tag with the public key is required`}},
+		{record: "k=rsa; v=DKIM1; p=" + strong, errors: []string{`"v" tag must be the first`}},
+		{record: "v=DKIM2; p=" + strong, errors: []string{`must be "DKIM1"`}},
+		{record: "v=DKIM1; k=dsa; p=" + strong, errors: []string{`unknown key type "dsa"`}},
+		{record: "v=DKIM1; p=!!!", errors: []string{"not valid base64"}},
+		{record: "v=DKIM1; p=" + ed, errors: []string{"not a valid RSA key"}},
+		{record: "v=DKIM1; k=ed25519; p=" + strong, errors: []string{"must be 32 bytes"}},
+		{record: "v=DKIM1; h=sha1:sha256; t=y; p=" + strong, warnings: []string{"sha1 is insecure", "testing mode"}},
+		{record: "v=DKIM1; p=" + strong + "; p=" + strong, errors: []string{`duplicate tag "p"`}},
+	}
+	for _, tt := range tests {
+		a := AnalyzeDKIM(tt.record)
+		checkProblems(t, tt.record, a.Problems, tt.errors, tt.warnings)
+	}
+}
+
+func TestIsDKIM(t *testing.T) {
+	for txt, want := range map[string]bool{
+		"v=DKIM1; k=rsa; p=MIIB": true,
+		"k=rsa; p=MIIB":          true,
+		"v=spf1 -all":            false,
+	} {
+		if got := IsDKIM(txt); got != want {
+			t.Errorf("IsDKIM(%q) = %v, want %v", txt, got, want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mailauth
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DMARC policies
+const (
+	PolicyNone       = "none"
+	PolicyQuarantine = "quarantine"
+	PolicyReject     = "reject"
+)
+
+var policyStrength = map[string]int{PolicyNone: 0, PolicyQuarantine: 1, PolicyReject: 2}
+
+// DMARC is the structured form of a DMARC record
+type DMARC struct {
+	Policy          string
+	SubdomainPolicy string
+	Percent         int // 0 means the default of 100
+	RUA             []string
+	RUF             []string
+	ADKIM           string
+	ASPF            string
+	FailureOptions  string
+}
+
+// String returns the text of the record.
+func (d DMARC) String() string {
+	tags := []string{"v=DMARC1", "p=" + d.Policy}
+	if d.SubdomainPolicy != "" {
+		tags = append(tags, "sp="+d.SubdomainPolicy)
+	}
+	if d.Percent != 0 && d.Percent != 100 {
+		tags = append(tags, "pct="+strconv.Itoa(d.Percent))
+	}
+	if len(d.RUA) > 0 {
+		tags = append(tags, "rua="+strings.Join(mailtoURIs(d.RUA), ","))
+	}
+	if len(d.RUF) > 0 {
+		tags = append(tags, "ruf="+strings.Join(mailtoURIs(d.RUF), ","))
+	}
+	if d.ADKIM != "" {
+		tags = append(tags, "adkim="+d.ADKIM)
+	}
+	if d.ASPF != "" {
+		tags = append(tags, "aspf="+d.ASPF)
+	}
+	if d.FailureOptions != "" {
+		tags = append(tags, "fo="+d.FailureOptions)
+	}
+	return strings.Join(tags, "; ")
+}
+
+// mailtoURIs adds the "mailto:" scheme to the bare addresses.
+func mailtoURIs(addrs []string) []string {
+	uris := make([]string, len(addrs))
+	for i, addr := range addrs {
+		if !strings.Contains(addr, ":") {
+			addr = "mailto:" + addr
+		}
+		uris[i] = addr
+	}
+	return uris
+}
+
+// IsDMARC reports whether the TXT record is a DMARC record.
+func IsDMARC(txt string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.Replace(txt, " ", "", -1)), "v=dmarc1")
+}
+
+// AnalyzeDMARC parses the DMARC record, explains its tags and reports the weak policies.
+func AnalyzeDMARC(record string) Analysis {
+	var a Analysis
+	tags := parseTags(record, &a)
+	if len(tags) == 0 || tags[0].Name != "v" || tags[0].Value != "DMARC1" {
+		a.errorf(`the record must start with "v=DMARC1"`)
+		return a
+	}
+
+	policy, subdomainPolicy, hasRUA := "", "", false
+	for _, t := range tags {
+		switch t.Name {
+		case "v":
+			a.explain(t.Text, "DMARC version 1")
+		case "p", "sp":
+			target := "the domain"
+			if t.Name == "sp" {
+				target = "the subdomains"
+				subdomainPolicy = strings.ToLower(t.Value)
+			} else {
+				policy = strings.ToLower(t.Value)
+			}
+			if _, ok := policyStrength[strings.ToLower(t.Value)]; !ok {
+				a.errorf(`unknown policy "%s", expected none, quarantine or reject`, t.Value)
+			}
+			a.explain(t.Text, "%s the failing messages of %s", policyAction(t.Value), target)
+		case "pct":
+			a.explain(t.Text, "apply the policy to %s%% of the failing messages", t.Value)
+			if pct, err := strconv.Atoi(t.Value); err != nil || pct < 0 || pct > 100 {
+				a.errorf(`"pct" must be a number from 0 to 100`)
+			} else if pct < 100 {
+				a.warnf("the policy is applied to %d%% of the messages only", pct)
+			}
+		case "rua", "ruf":
+			kind := "aggregate"
+			if t.Name == "ruf" {
+				kind = "failure"
+			} else {
+				hasRUA = true
+			}
+			a.explain(t.Text, "send %s reports to %s", kind, t.Value)
+			for _, uri := range strings.Split(t.Value, ",") {
+				uri = strings.ToLower(strings.TrimSpace(uri))
+				if !strings.HasPrefix(uri, "mailto:") && !strings.HasPrefix(uri, "https:") {
+					a.errorf(`report address "%s" must be a mailto: or https: URI`, uri)
+				}
+			}
+		case "adkim", "aspf":
+			mechanism := strings.ToUpper(strings.TrimPrefix(t.Name, "a"))
+			switch t.Value {
+			case "r":
+				a.explain(t.Text, "relaxed %s alignment", mechanism)
+			case "s":
+				a.explain(t.Text, "strict %s alignment", mechanism)
+			default:
+				a.errorf(`"%s" must be "r" or "s"`, t.Name)
+			}
+		case "fo":
+			a.explain(t.Text, "failure report options")
+			for _, o := range strings.Split(t.Value, ":") {
+				if o != "0" && o != "1" && o != "d" && o != "s" {
+					a.errorf(`unknown failure report option "%s"`, o)
+				}
+			}
+		case "ri":
+			a.explain(t.Text, "aggregate report interval in seconds")
+			if _, err := strconv.Atoi(t.Value); err != nil {
+				a.errorf(`"ri" must be a number`)
+			}
+		case "rf":
+			a.explain(t.Text, "failure report format")
+		default:
+			a.explain(t.Text, "unknown tag, ignored")
+			a.warnf(`unknown tag "%s"`, t.Name)
+		}
+	}
+
+	switch {
+	case policy == "":
+		a.errorf(`the "p" tag is required`)
+	case policy == PolicyNone:
+		a.warnf(`"p=none" only monitors, spoofed messages are still delivered`)
+	}
+	if subdomainPolicy != "" && policy != "" && policyStrength[subdomainPolicy] < policyStrength[policy] {
+		a.warnf(`the subdomain policy "%s" is weaker than the domain policy "%s"`, subdomainPolicy, policy)
+	}
+	if !hasRUA {
+		a.warnf(`no "rua" tag, you will not receive aggregate reports`)
+	}
+	return a
+}
+
+func policyAction(policy string) string {
+	switch strings.ToLower(policy) {
+	case PolicyQuarantine:
+		return "quarantine"
+	case PolicyReject:
+		return "reject"
+	}
+	return "deliver and report"
+}
@@ -0,0 +1,76 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mailauth
+
+import "testing"
+
+func TestAnalyzeDMARC(t *testing.T) {
+	const rua = "; rua=mailto:dmarc@example.com"
+	tests := []problemCase{
+		{record: "v=DMARC1; p=reject" + rua},
+		{record: "v=DMARC1; p=quarantine; sp=reject; adkim=s; aspf=r; fo=1:d; ri=86400" + rua},
+		{record: "v=DMARC1; p=none" + rua, warnings: []string{`"p=none" only monitors`}},
+		{record: "v=DMARC1; p=reject", warnings: []string{`no "rua" tag`}},
+		{record: "p=reject; v=DMARC1" + rua, errors: []string{`must start with "v=DMARC1"`}},
+		{record: "v=DMARC1" + rua, errors: []string{`"p" tag is required`}},
+		{record: "v=DMARC1; p=block" + rua, errors: []string{`unknown policy "block"`}},
+		{record: "v=DMARC1; p=reject; sp=none" + rua, warnings: []string{`subdomain policy "none" is weaker`}},
+		{record: "v=DMARC1; p=reject; pct=50" + rua, warnings: []string{"50% of the messages"}},
+		{record: "v=DMARC1; p=reject; pct=150" + rua, errors: []string{`"pct" must be a number from 0 to 100`}},
+		{record: "v=DMARC1; p=reject; rua=dmarc@example.com", errors: []string{"must be a mailto: or https: URI"}},
+		{record: "v=DMARC1; p=reject; adkim=x" + rua, errors: []string{`"adkim" must be "r" or "s"`}},
+		{record: "v=DMARC1; p=reject; fo=2" + rua, errors: []string{`unknown failure report option "2"`}},
+		{record: "v=DMARC1; p=reject; foo=bar" + rua, warnings: []string{`unknown tag "foo"`}},
+	}
+	for _, tt := range tests {
+		a := AnalyzeDMARC(tt.record)
+		checkProblems(t, tt.record, a.Problems, tt.errors, tt.warnings)
+	}
+}
+
+func TestDMARCString(t *testing.T) {
+	tests := []struct {
+		dmarc DMARC
+		want  string
+	}{
+		{DMARC{Policy: PolicyQuarantine, Percent: 100, RUA: []string{"dmarc@example.com"}},
+			"v=DMARC1; p=quarantine; rua=mailto:dmarc@example.com"},
+		{DMARC{Policy: PolicyReject, SubdomainPolicy: PolicyQuarantine, Percent: 25, RUF: []string{"https://example.com/r"}, ADKIM: "s"},
+			"v=DMARC1; p=reject; sp=quarantine; pct=25; ruf=https://example.com/r; adkim=s"},
+	}
+	for _, tt := range tests {
+		if got := tt.dmarc.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestIsDMARC(t *testing.T) {
+	for txt, want := range map[string]bool{
+		"v=DMARC1; p=none":   true,
+		"v = DMARC1; p=none": true,
+		"v=spf1 -all":        false,
+	} {
+		if got := IsDMARC(txt); got != want {
+			t.Errorf("IsDMARC(%q) = %v, want %v", txt, got, want)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mailauth
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	spfVersion = "v=spf1"
+
+	// SPFLookupLimit is the maximum number of DNS lookups allowed while evaluating an SPF record (RFC 7208)
+	SPFLookupLimit = 10
+)
+
+var spfQualifiers = map[byte]string{'+': "pass", '-': "fail", '~': "softfail", '?': "neutral"}
+
+// LookupFunc returns the TXT records of the name
+type LookupFunc func(name string) ([]string, error)
+
+// SPF is the structured form of an SPF record
+type SPF struct {
+	A        bool
+	MX       bool
+	IP4      []string
+	IP6      []string
+	Include  []string
+	Redirect string
+	All      string // qualified "all" mechanism, e.g. "~all"
+}
+
+// String returns the text of the record.
+func (s SPF) String() string {
+	terms := []string{spfVersion}
+	if s.A {
+		terms = append(terms, "a")
+	}
+	if s.MX {
+		terms = append(terms, "mx")
+	}
+	for _, ip := range s.IP4 {
+		terms = append(terms, "ip4:"+ip)
+	}
+	for _, ip := range s.IP6 {
+		terms = append(terms, "ip6:"+ip)
+	}
+	for _, domain := range s.Include {
+		terms = append(terms, "include:"+domain)
+	}
+	if s.Redirect != "" {
+		terms = append(terms, "redirect="+s.Redirect)
+	}
+	if s.All != "" {
+		terms = append(terms, s.All)
+	}
+	return strings.Join(terms, " ")
+}
+
+// IsSPF reports whether the TXT record is an SPF record.
+func IsSPF(txt string) bool {
+	txt = strings.ToLower(strings.TrimSpace(txt))
+	return txt == spfVersion || strings.HasPrefix(txt, spfVersion+" ")
+}
+
+// AnalyzeSPF parses the SPF record and explains its terms.
+func AnalyzeSPF(record string) Analysis {
+	var a Analysis
+	terms := strings.Fields(record)
+	if len(terms) == 0 || strings.ToLower(terms[0]) != spfVersion {
+		a.errorf(`the record must start with "%s"`, spfVersion)
+		return a
+	}
+	a.explain(terms[0], "SPF version 1")
+
+	var all, redirect string
+	modifiers := make(map[string]bool)
+	for _, term := range terms[1:] {
+		if all != "" {
+			a.warnf(`"%s" after "%s" is never evaluated`, term, all)
+		}
+
+		if eq := strings.Index(term, "="); eq > 0 && !strings.ContainsAny(term[:eq], ":/") {
+			name, value := strings.ToLower(term[:eq]), term[eq+1:]
+			if modifiers[name] {
+				a.errorf(`duplicate modifier "%s"`, name)
+			}
+			modifiers[name] = true
+			switch name {
+			case "redirect":
+				redirect = value
+				a.explain(term, "use the SPF record of %s if nothing else matches", value)
+			case "exp":
+				a.explain(term, "explanation for the rejected messages is in the TXT record of %s", value)
+			default:
+				a.explain(term, "unknown modifier, ignored")
+			}
+			if value == "" {
+				a.errorf(`modifier "%s" has no value`, name)
+			}
+			continue
+		}
+
+		result, mechanism := "pass", term
+		if q, ok := spfQualifiers[term[0]]; ok {
+			result, mechanism = q, term[1:]
+		}
+		name, value := mechanism, ""
+		if i := strings.IndexAny(mechanism, ":/"); i >= 0 {
+			name, value = mechanism[:i], strings.TrimPrefix(mechanism[i:], ":")
+		}
+		switch strings.ToLower(name) {
+		case "all":
+			all = term
+			a.explain(term, "%s for all other servers", result)
+			switch result {
+			case "pass":
+				a.errorf(`"%s" allows any server to send mail for the domain`, term)
+			case "neutral":
+				a.warnf(`"%s" does not protect the domain, use "~all" or "-all"`, term)
+			}
+		case "include":
+			if value == "" {
+				a.errorf(`"%s" requires a domain`, term)
+			}
+			a.explain(term, "%s for the servers allowed by the SPF record of %s", result, value)
+		case "a", "mx":
+			target := "the domain"
+			if value != "" {
+				target = value
+			}
+			a.explain(term, "%s for the %s addresses of %s", result, strings.ToUpper(name), target)
+		case "ip4", "ip6":
+			v4 := strings.ToLower(name) == "ip4"
+			if !validNetwork(value, v4) {
+				family := "IPv6"
+				if v4 {
+					family = "IPv4"
+				}
+				a.errorf(`"%s" is not a valid %s address or network`, value, family)
+			}
+			a.explain(term, "%s for %s", result, value)
+		case "exists":
+			if value == "" {
+				a.errorf(`"%s" requires a domain`, term)
+			}
+			a.explain(term, "%s if %s resolves", result, value)
+		case "ptr":
+			a.explain(term, "%s for the servers whose reverse DNS name is in the domain", result)
+			a.warnf(`"%s" is slow and deprecated by RFC 7208`, term)
+		default:
+			a.errorf(`unknown mechanism "%s"`, term)
+		}
+	}
+
+	if all != "" && redirect != "" {
+		a.warnf(`"redirect=%s" is ignored because of "%s"`, redirect, all)
+	}
+	if all == "" && redirect == "" {
+		a.warnf(`no "all" mechanism, the result for other servers is neutral`)
+	}
+	if len(record) > 255 {
+		a.warnf("the record is longer than 255 characters and must be split into several strings")
+	}
+	return a
+}
+
+func validNetwork(value string, v4 bool) bool {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		var err error
+		if ip, _, err = net.ParseCIDR(value); err != nil {
+			return false
+		}
+	}
+	return (ip.To4() != nil) == v4
+}
+
+// CountSPFLookups counts the DNS lookups needed to evaluate the record including the nested
+// include and redirect records fetched with lookup.
+func CountSPFLookups(record string, lookup LookupFunc) (int, []Problem) {
+	var a Analysis
+	count := countLookups(record, cachedLookup(lookup), make(map[string]bool), &a)
+	return count, a.Problems
+}
+
+func countLookups(record string, lookup LookupFunc, visited map[string]bool, a *Analysis) int {
+	count := 0
+	for _, term := range strings.Fields(record)[1:] {
+		term = strings.ToLower(strings.TrimLeft(term, "+-~?"))
+		var nested string
+		switch {
+		case strings.HasPrefix(term, "include:"):
+			nested = strings.TrimPrefix(term, "include:")
+		case strings.HasPrefix(term, "redirect="):
+			nested = strings.TrimPrefix(term, "redirect=")
+		case term == "a" || term == "mx" || term == "ptr" ||
+			strings.HasPrefix(term, "a:") || strings.HasPrefix(term, "a/") ||
+			strings.HasPrefix(term, "mx:") || strings.HasPrefix(term, "mx/") ||
+			strings.HasPrefix(term, "ptr:") || strings.HasPrefix(term, "exists:"):
+			count++
+			continue
+		default:
+			continue
+		}
+
+		count++
+		// only the names on the path from the top record form a loop, the same record
+		// included by two branches is evaluated and counted twice
+		if visited[nested] {
+			a.errorf("SPF record of %s is included in a loop", nested)
+			continue
+		}
+		txt, err := nestedSPF(nested, lookup)
+		if err != nil {
+			a.errorf("%s", err)
+			continue
+		}
+		visited[nested] = true
+		count += countLookups(txt, lookup, visited, a)
+		delete(visited, nested)
+	}
+	return count
+}
+
+// cachedLookup looks up every name once, the records included by several branches are shared.
+func cachedLookup(lookup LookupFunc) LookupFunc {
+	type result struct {
+		records []string
+		err     error
+	}
+	cache := make(map[string]result)
+	return func(domain string) ([]string, error) {
+		r, ok := cache[domain]
+		if !ok {
+			r.records, r.err = lookup(domain)
+			cache[domain] = r
+		}
+		return r.records, r.err
+	}
+}
+
+func nestedSPF(domain string, lookup LookupFunc) (string, error) {
+	records, err := lookup(domain)
+	if err != nil {
+		return "", fmt.Errorf("cannot look up the SPF record of %s: %s", domain, err)
+	}
+	var spf []string
+	for _, r := range records {
+		if IsSPF(r) {
+			spf = append(spf, r)
+		}
+	}
+	switch len(spf) {
+	case 0:
+		return "", fmt.Errorf("%s has no SPF record", domain)
+	case 1:
+		return spf[0], nil
+	}
+	return "", errors.New(domain + " has several SPF records")
+}
@@ -0,0 +1,106 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mailauth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAnalyzeSPF(t *testing.T) {
+	tests := []problemCase{
+		{record: "v=spf1 mx -all"},
+		{record: "v=spf1 ip4:192.0.2.0/24 ip6:2001:db8::/32 include:_spf.example.net ~all"},
+		{record: "v=spf1 redirect=_spf.example.net"},
+		{record: "spf1 -all", errors: []string{`must start with "v=spf1"`}},
+		{record: "v=spf1 +all", errors: []string{"allows any server"}},
+		{record: "v=spf1 ?all", warnings: []string{"does not protect"}},
+		{record: "v=spf1 mx", warnings: []string{`no "all" mechanism`}},
+		{record: "v=spf1 -all mx", warnings: []string{"never evaluated"}},
+		{record: "v=spf1 ip4:192.0.2.256 ip6:192.0.2.1 -all", errors: []string{"not a valid IPv4", "not a valid IPv6"}},
+		{record: "v=spf1 include: -all", errors: []string{"requires a domain"}},
+		{record: "v=spf1 ptr -all", warnings: []string{"deprecated"}},
+		{record: "v=spf1 mxx -all", errors: []string{`unknown mechanism "mxx"`}},
+		{record: "v=spf1 redirect=a.example redirect=b.example", errors: []string{`duplicate modifier "redirect"`}},
+		{record: "v=spf1 redirect=a.example -all", warnings: []string{"is ignored"}},
+	}
+	for _, tt := range tests {
+		a := AnalyzeSPF(tt.record)
+		checkProblems(t, tt.record, a.Problems, tt.errors, tt.warnings)
+	}
+}
+
+func TestSPFString(t *testing.T) {
+	s := SPF{MX: true, IP4: []string{"192.0.2.1"}, Include: []string{"_spf.example.net"}, All: "-all"}
+	want := "v=spf1 mx ip4:192.0.2.1 include:_spf.example.net -all"
+	if got := s.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if a := AnalyzeSPF(s.String()); len(a.Problems) != 0 {
+		t.Errorf("problems of the built record: %v", a.Problems)
+	}
+}
+
+func TestCountSPFLookups(t *testing.T) {
+	zone := map[string][]string{
+		"a.example":             {"v=spf1 include:_spf.google.com -all"},
+		"b.example":             {"google-site-verification=x", "v=spf1 include:_spf.google.com mx -all"},
+		"_spf.google.com":       {"v=spf1 include:_netblocks.google.com ~all"},
+		"_netblocks.google.com": {"v=spf1 ip4:192.0.2.0/24 ~all"},
+		"loop1.example":         {"v=spf1 include:loop2.example -all"},
+		"loop2.example":         {"v=spf1 include:loop1.example -all"},
+		"double.example":        {"v=spf1 -all", "v=spf1 ~all"},
+		"redirect.example":      {"v=spf1 redirect=a.example"},
+	}
+	lookups := make(map[string]int)
+	lookup := func(name string) ([]string, error) {
+		lookups[name]++
+		if records, ok := zone[name]; ok {
+			return records, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	tests := []struct {
+		record string
+		count  int
+		errors []string
+	}{
+		{record: "v=spf1 ip4:192.0.2.1 -all", count: 0},
+		{record: "v=spf1 a mx a:mail.example mx/24 ptr exists:%{i}.bl.example -all", count: 6},
+		// the same record included by two branches is counted twice and is not a loop
+		{record: "v=spf1 include:a.example include:b.example -all", count: 7},
+		{record: "v=spf1 redirect=redirect.example", count: 4},
+		{record: "v=spf1 include:loop1.example -all", count: 3, errors: []string{"loop1.example is included in a loop"}},
+		{record: "v=spf1 include:missing.example -all", count: 1, errors: []string{"cannot look up"}},
+		{record: "v=spf1 include:_netblocks.google.com include:double.example -all", count: 2, errors: []string{"several SPF records"}},
+	}
+	for _, tt := range tests {
+		count, problems := CountSPFLookups(tt.record, lookup)
+		if count != tt.count {
+			t.Errorf("%q: %d lookups, want %d", tt.record, count, tt.count)
+		}
+		checkProblems(t, tt.record, problems, tt.errors, nil)
+	}
+	if lookups["_spf.google.com"] != 2 {
+		t.Errorf("_spf.google.com looked up %d times, want once per CountSPFLookups call", lookups["_spf.google.com"])
+	}
+}
@@ -20,16 +20,8 @@
 
 package main
 
-import (
-	"fmt"
-	"os"
-
-	"github.com/lexty/yandex-dns-cli-manager/cmd"
-)
+import "github.com/lexty/yandex-dns-cli-manager/cmd"
 
 func main() {
-	if err := cmd.RootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(-1)
-	}
+	cmd.Execute()
 }
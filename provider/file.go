@@ -0,0 +1,229 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+)
+
+const (
+	NameFile = "file"
+
+	defaultTTL = 21600
+)
+
+// File is the provider keeping the records in a local JSON file,
+// useful to rehearse changes and to run the CLI in tests
+type File struct {
+	Path string
+}
+
+type fileZones struct {
+	NextId  int                     `json:"next_id"`
+	Domains map[string][]api.Record `json:"domains"`
+}
+
+func (p *File) load() (*fileZones, error) {
+	zones := &fileZones{NextId: 1, Domains: make(map[string][]api.Record)}
+	data, err := ioutil.ReadFile(p.Path)
+	if os.IsNotExist(err) {
+		return zones, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, zones); err != nil {
+		return nil, err
+	}
+	if zones.Domains == nil {
+		zones.Domains = make(map[string][]api.Record)
+	}
+	return zones, nil
+}
+
+func (p *File) save(zones *fileZones) error {
+	data, err := json.MarshalIndent(zones, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(p.Path, append(data, '\n'), 0600)
+}
+
+func fileResponse(res api.Response) (api.Response, error) {
+	res.Success = "ok"
+	data, err := json.Marshal(res)
+	res.Json = string(data)
+	return res, err
+}
+
+func (p *File) List(domain string) (api.Response, error) {
+	zones, err := p.load()
+	if err != nil {
+		return api.Response{}, err
+	}
+	return fileResponse(api.Response{Domain: domain, Records: zones.Domains[domain]})
+}
+
+func (p *File) Create(domain string, r *api.Record) (api.Response, error) {
+	if r.RecordType == "" {
+		return api.Response{}, api.NewApiError("no_type", 0)
+	}
+	if !p.Capabilities().SupportsType(r.RecordType) {
+		return api.Response{}, api.NewApiError("bad_type", 0)
+	}
+	zones, err := p.load()
+	if err != nil {
+		return api.Response{}, err
+	}
+
+	rec := *r
+	rec.RecordId = zones.NextId
+	rec.RecordType = strings.ToUpper(rec.RecordType)
+	rec.Domain = domain
+	if rec.Subdomain == "" {
+		rec.Subdomain = "@"
+	}
+	if rec.TTL == 0 {
+		rec.TTL = defaultTTL
+	}
+	rec.FQDN = fqdn(rec.Subdomain, domain)
+	zones.NextId++
+	zones.Domains[domain] = append(zones.Domains[domain], rec)
+	if err := p.save(zones); err != nil {
+		return api.Response{}, err
+	}
+	*r = rec
+	return fileResponse(api.Response{Domain: domain, Record: rec, RecordId: rec.RecordId})
+}
+
+// Update changes the fields set in r (see api.Record.Sets) like the PDD API does,
+// a cleared TTL is reset to the default.
+func (p *File) Update(domain string, r *api.Record) (api.Response, error) {
+	zones, err := p.load()
+	if err != nil {
+		return api.Response{}, err
+	}
+	rec := findRecord(zones.Domains[domain], r.RecordId)
+	if rec == nil {
+		return api.Response{}, api.NewApiError("bad_record_id", 0)
+	}
+	if r.Sets(api.FieldSubdomain, r.Subdomain != "") {
+		rec.Subdomain = r.Subdomain
+		if rec.Subdomain == "" {
+			rec.Subdomain = "@"
+		}
+		rec.FQDN = fqdn(rec.Subdomain, domain)
+	}
+	if r.Sets(api.FieldContent, r.Content != "") {
+		rec.Content = r.Content
+	}
+	if r.Sets(api.FieldTTL, r.TTL != 0) {
+		rec.TTL = r.TTL
+		if rec.TTL == 0 {
+			rec.TTL = defaultTTL
+		}
+	}
+	if r.Sets(api.FieldPriority, r.Priority != nil) {
+		rec.Priority = r.Priority
+	}
+	if r.Sets(api.FieldWeight, r.Weight != 0) {
+		rec.Weight = r.Weight
+	}
+	if r.Sets(api.FieldPort, r.Port != 0) {
+		rec.Port = r.Port
+	}
+	if r.Sets(api.FieldTarget, r.Target != "") {
+		rec.Target = r.Target
+	}
+	if r.Sets(api.FieldAdminMail, r.AdminMail != "") {
+		rec.AdminMail = r.AdminMail
+	}
+	if r.Sets(api.FieldRefresh, r.Refresh != 0) {
+		rec.Refresh = r.Refresh
+	}
+	if r.Sets(api.FieldRetry, r.Retry != 0) {
+		rec.Retry = r.Retry
+	}
+	if r.Sets(api.FieldExpire, r.Expire != 0) {
+		rec.Expire = r.Expire
+	}
+	if r.Sets(api.FieldNegCache, r.NegCache != 0) {
+		rec.NegCache = r.NegCache
+	}
+	if err := p.save(zones); err != nil {
+		return api.Response{}, err
+	}
+	*r = *rec
+	return fileResponse(api.Response{Domain: domain, Record: *rec, RecordId: rec.RecordId})
+}
+
+func (p *File) Delete(domain string, id int) (api.Response, error) {
+	zones, err := p.load()
+	if err != nil {
+		return api.Response{}, err
+	}
+	records := zones.Domains[domain]
+	for i := range records {
+		if records[i].RecordId == id {
+			zones.Domains[domain] = append(records[:i], records[i+1:]...)
+			if err := p.save(zones); err != nil {
+				return api.Response{}, err
+			}
+			return fileResponse(api.Response{Domain: domain, RecordId: id})
+		}
+	}
+	return api.Response{}, api.NewApiError("bad_record_id", 0)
+}
+
+func (p *File) Capabilities() Capabilities {
+	return Capabilities{RecordTypes: allTypes}
+}
+
+func findRecord(records []api.Record, id int) *api.Record {
+	for i := range records {
+		if records[i].RecordId == id {
+			return &records[i]
+		}
+	}
+	return nil
+}
+
+func fqdn(subdomain, domain string) string {
+	if subdomain == "@" {
+		return domain
+	}
+	return subdomain + "." + domain
+}
+
+func init() {
+	Register(NameFile, func(settings Settings) (Provider, error) {
+		if settings("file-path") == "" {
+			return nil, errors.New("file-path is not set")
+		}
+		return &File{Path: settings("file-path")}, nil
+	})
+}
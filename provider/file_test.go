@@ -0,0 +1,139 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package provider
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+)
+
+func newTestFile(t *testing.T) *File {
+	return &File{Path: filepath.Join(t.TempDir(), "zones.json")}
+}
+
+func TestFileCreateList(t *testing.T) {
+	p := newTestFile(t)
+	r := api.Record{RecordType: "a", Content: "192.0.2.1"}
+	if _, err := p.Create("example.com", &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.RecordId != 1 || r.RecordType != "A" || r.Subdomain != "@" || r.FQDN != "example.com" || r.TTL != defaultTTL {
+		t.Errorf("created record = %+v", r)
+	}
+	mx := api.Record{RecordType: "MX", Subdomain: "mail", Content: "mx.example.com.", Priority: "10"}
+	if _, err := p.Create("example.com", &mx); err != nil {
+		t.Fatal(err)
+	}
+	if mx.RecordId != 2 || mx.FQDN != "mail.example.com" {
+		t.Errorf("created record = %+v", mx)
+	}
+
+	// the records are kept in the file
+	res, err := (&File{Path: p.Path}).List("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Records) != 2 || res.Records[1].Content != "mx.example.com." || api.PriorityString(res.Records[1].Priority) != "10" {
+		t.Errorf("listed records = %+v", res.Records)
+	}
+	if res.Json == "" {
+		t.Error("the response has no JSON")
+	}
+	if res, _ := p.List("example.org"); len(res.Records) != 0 {
+		t.Errorf("records of another domain = %+v", res.Records)
+	}
+}
+
+func TestFileCreateRejectsType(t *testing.T) {
+	p := newTestFile(t)
+	for _, recordType := range []string{"", "PTR"} {
+		if _, err := p.Create("example.com", &api.Record{RecordType: recordType, Content: "x"}); err == nil {
+			t.Errorf("Create(%q) succeeded", recordType)
+		}
+	}
+}
+
+func TestFileUpdateChangesOnlySetFields(t *testing.T) {
+	p := newTestFile(t)
+	srv := api.Record{RecordType: "SRV", Subdomain: "_sip._tcp", Target: "sip.example.com.", Priority: "10", Weight: 5, Port: 5060, TTL: 600}
+	if _, err := p.Create("example.com", &srv); err != nil {
+		t.Fatal(err)
+	}
+
+	edit := api.Record{RecordId: srv.RecordId, Port: 5061}
+	if _, err := p.Update("example.com", &edit); err != nil {
+		t.Fatal(err)
+	}
+	want := srv
+	want.Port = 5061
+	if edit != want {
+		t.Errorf("updated record = %+v, want %+v", edit, want)
+	}
+
+	if _, err := p.Update("example.com", &api.Record{RecordId: 99, TTL: 300}); err == nil {
+		t.Error("Update of an unknown record succeeded")
+	}
+}
+
+func TestFileUpdateClearsSetFields(t *testing.T) {
+	p := newTestFile(t)
+	srv := api.Record{RecordType: "SRV", Subdomain: "_sip._tcp", Target: "sip.example.com.", Priority: "10", Weight: 5, Port: 5060, TTL: 600}
+	if _, err := p.Create("example.com", &srv); err != nil {
+		t.Fatal(err)
+	}
+
+	edit := api.Record{RecordId: srv.RecordId, Port: 5061, Fields: api.FieldWeight | api.FieldTTL}
+	if _, err := p.Update("example.com", &edit); err != nil {
+		t.Fatal(err)
+	}
+	want := srv
+	want.Weight, want.TTL = 0, defaultTTL
+	if edit != want {
+		t.Errorf("updated record = %+v, want %+v", edit, want)
+	}
+
+	edit = api.Record{RecordId: srv.RecordId, Fields: api.FieldSubdomain}
+	if _, err := p.Update("example.com", &edit); err != nil {
+		t.Fatal(err)
+	}
+	if edit.Subdomain != "@" || edit.FQDN != "example.com" {
+		t.Errorf("record with a cleared subdomain = %+v", edit)
+	}
+}
+
+func TestFileDelete(t *testing.T) {
+	p := newTestFile(t)
+	r := api.Record{RecordType: "TXT", Content: "v=spf1 -all"}
+	if _, err := p.Create("example.com", &r); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Delete("example.com", r.RecordId); err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := p.List("example.com"); len(res.Records) != 0 {
+		t.Errorf("records after Delete = %+v", res.Records)
+	}
+	if _, err := p.Delete("example.com", r.RecordId); err == nil {
+		t.Error("second Delete succeeded")
+	}
+}
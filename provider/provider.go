@@ -0,0 +1,95 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package provider defines the interface of the DNS providers and the registry of their implementations.
+package provider
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/lexty/yandex-dns-cli-manager/api"
+)
+
+var (
+	ErrNoToken = errors.New("admin token is not set")
+	ErrNoOrgId = errors.New("organization ID is not set")
+)
+
+// Capabilities describes what the provider supports
+type Capabilities struct {
+	RecordTypes   []string // supported record types
+	RequiresToken bool     // whether the provider needs the admin token
+}
+
+// SupportsType reports whether the record type is supported.
+func (c Capabilities) SupportsType(t string) bool {
+	for _, supported := range c.RecordTypes {
+		if strings.ToUpper(t) == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// Provider manages the DNS records of the domains.
+// The responses keep the raw JSON of the answer in Response.Json.
+type Provider interface {
+	List(domain string) (api.Response, error)
+	Create(domain string, r *api.Record) (api.Response, error)
+	Update(domain string, r *api.Record) (api.Response, error)
+	Delete(domain string, id int) (api.Response, error)
+	Capabilities() Capabilities
+}
+
+// Settings returns the value of the setting, e.g. "admin-token"
+type Settings func(key string) string
+
+// Factory creates the provider from the settings
+type Factory func(settings Settings) (Provider, error)
+
+var factories = make(map[string]Factory)
+
+// Register makes the provider available by the name.
+func Register(name string, factory Factory) {
+	factories[name] = factory
+}
+
+// New creates the registered provider.
+func New(name string, settings Settings) (Provider, error) {
+	factory, ok := factories[name]
+	if !ok {
+		return nil, errors.New("unknown provider " + name + " (available: " + strings.Join(Names(), ", ") + ")")
+	}
+	return factory(settings)
+}
+
+// Names returns the sorted names of the registered providers.
+func Names() []string {
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var allTypes = []string{api.Type_A, api.Type_AAAA, api.Type_CNAME, api.Type_MX, api.Type_NS, api.Type_SOA, api.Type_SRV, api.Type_TXT}
@@ -0,0 +1,86 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func settingsOf(values map[string]string) Settings {
+	return func(key string) string {
+		return values[key]
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]string
+		err      error
+	}{
+		{NamePDD, map[string]string{}, ErrNoToken},
+		{NameYandex360, map[string]string{"admin-token": "token"}, ErrNoOrgId},
+		{NameYandex360, map[string]string{"admin-token": "token", "org-id": "42"}, nil},
+		{NameFile, map[string]string{"file-path": "zones.json"}, nil},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.name, settingsOf(tt.settings)); err != tt.err {
+			t.Errorf("New(%s, %v) error = %v, want %v", tt.name, tt.settings, err, tt.err)
+		}
+	}
+	if _, err := New("unknown", settingsOf(nil)); err == nil {
+		t.Error("New(unknown) succeeded")
+	}
+}
+
+// TestEndpointPerProvider checks that two providers built with different endpoints,
+// e.g. the source and the target of "clone", do not redirect each other's requests.
+func TestEndpointPerProvider(t *testing.T) {
+	serve := func(content string) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"domain":"example.com","records":[{"record_id":1,"type":"A","content":"%s"}],"success":"ok"}`, content)
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	source, target := serve("192.0.2.1"), serve("192.0.2.2")
+
+	from, err := New(NamePDD, settingsOf(map[string]string{"admin-token": "a", "endpoint": source.URL + "/"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := New(NamePDD, settingsOf(map[string]string{"admin-token": "b", "endpoint": target.URL + "/"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p, want := range map[Provider]string{from: "192.0.2.1", to: "192.0.2.2"} {
+		res, err := p.List("example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Records) != 1 || res.Records[0].Content != want {
+			t.Errorf("records = %+v, want the content %s", res.Records, want)
+		}
+	}
+}
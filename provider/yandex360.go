@@ -0,0 +1,71 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package provider
+
+import "github.com/lexty/yandex-dns-cli-manager/api"
+
+const NameYandex360 = "yandex360"
+
+// Yandex360 is the provider of the Yandex 360 Directory DNS API
+type Yandex360 struct {
+	Token    string
+	OrgId    string
+	Endpoint string // URL prefix of the API, the default one if empty
+}
+
+func (p *Yandex360) List(domain string) (api.Response, error) {
+	return p.client().GetList(domain)
+}
+
+func (p *Yandex360) Create(domain string, r *api.Record) (api.Response, error) {
+	return p.client().AddRecord(r, domain)
+}
+
+func (p *Yandex360) Update(domain string, r *api.Record) (api.Response, error) {
+	return p.client().EditRecord(r, domain)
+}
+
+func (p *Yandex360) Delete(domain string, id int) (api.Response, error) {
+	return p.client().DeleteRecordById(id, domain)
+}
+
+func (p *Yandex360) client() api.Client360 {
+	return api.Client360{Endpoint: p.Endpoint, OrgId: p.OrgId, Token: p.Token}
+}
+
+func (p *Yandex360) Capabilities() Capabilities {
+	return Capabilities{
+		RecordTypes:   []string{api.Type_A, api.Type_AAAA, api.Type_CNAME, api.Type_MX, api.Type_NS, api.Type_SRV, api.Type_TXT},
+		RequiresToken: true,
+	}
+}
+
+func init() {
+	Register(NameYandex360, func(settings Settings) (Provider, error) {
+		if settings("admin-token") == "" {
+			return nil, ErrNoToken
+		}
+		if settings("org-id") == "" {
+			return nil, ErrNoOrgId
+		}
+		return &Yandex360{Token: settings("admin-token"), OrgId: settings("org-id"), Endpoint: settings("endpoint")}, nil
+	})
+}
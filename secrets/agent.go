@@ -0,0 +1,97 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package secrets
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ServeAgent hands out the key to the clients connecting to the unix socket until the timeout expires.
+func ServeAgent(socket string, key []byte, timeout time.Duration) error {
+	l, err := listenPrivate(socket)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socket)
+	time.AfterFunc(timeout, func() { l.Close() })
+
+	encoded := base64.StdEncoding.EncodeToString(key) + "\n"
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			// the listener is closed when the timeout expires
+			return nil
+		}
+		conn.SetDeadline(time.Now().Add(time.Second))
+		conn.Write([]byte(encoded))
+		conn.Close()
+	}
+}
+
+// listenPrivate listens on the unix socket accessible only by the user. The socket is created
+// in a private directory and moved to its path once its mode is set, so it is never exposed
+// with the permissions of the umask.
+func listenPrivate(socket string) (net.Listener, error) {
+	dir, err := ioutil.TempDir(filepath.Dir(socket), ".agent")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "sock")
+	l, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	os.Remove(socket)
+	if err := os.Rename(tmp, socket); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// AgentKey requests the key from the agent listening on the unix socket.
+func AgentKey(socket string) ([]byte, error) {
+	conn, err := net.DialTimeout("unix", socket, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(line))
+}
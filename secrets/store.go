@@ -0,0 +1,278 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package secrets implements an encrypted local store of the admin tokens.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+const (
+	storeVersion      = 1
+	kdfPBKDF2         = "pbkdf2-sha256"
+	defaultIterations = 200000
+	keyLength         = 32
+	saltLength        = 16
+	checkValue        = "yandex-dns-cli-manager"
+	checkName         = "\x00check"
+)
+
+var (
+	ErrLocked        = errors.New("token store is locked")
+	ErrBadPassphrase = errors.New("wrong passphrase for the token store")
+	ErrNotFound      = errors.New("token not found in the store")
+)
+
+type sealed struct {
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// Store holds the tokens encrypted with AES-256-GCM and a key derived from a passphrase
+type Store struct {
+	Version    int               `json:"version"`
+	KDF        string            `json:"kdf"`
+	Iterations int               `json:"iterations"`
+	Salt       []byte            `json:"salt"`
+	Check      *sealed           `json:"check,omitempty"`
+	Tokens     map[string]sealed `json:"tokens"`
+
+	path string
+	key  []byte
+}
+
+// Open reads the store from the file. A missing file gives an empty store.
+func Open(path string) (*Store, error) {
+	s := &Store{
+		Version:    storeVersion,
+		KDF:        kdfPBKDF2,
+		Iterations: defaultIterations,
+		Tokens:     make(map[string]sealed),
+		path:       path,
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.KDF != kdfPBKDF2 {
+		return nil, errors.New("unsupported key derivation function " + s.KDF)
+	}
+	if s.Tokens == nil {
+		s.Tokens = make(map[string]sealed)
+	}
+	return s, nil
+}
+
+// Save writes the store to its file readable only by the owner.
+func (s *Store) Save() error {
+	data, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.path, append(data, '\n'), 0600)
+}
+
+// IsNew reports whether the passphrase of the store has not been set yet.
+func (s *Store) IsNew() bool {
+	return s.Check == nil
+}
+
+// Unlock derives the key from the passphrase and verifies it.
+// The passphrase of a new store is set by the first call.
+func (s *Store) Unlock(passphrase string) error {
+	if s.IsNew() {
+		s.Salt = make([]byte, saltLength)
+		if _, err := rand.Read(s.Salt); err != nil {
+			return err
+		}
+		s.key = deriveKey(passphrase, s.Salt, s.Iterations)
+		check, err := s.seal(checkName, checkValue)
+		if err != nil {
+			return err
+		}
+		s.Check = &check
+		return nil
+	}
+	return s.UnlockWithKey(deriveKey(passphrase, s.Salt, s.Iterations))
+}
+
+// UnlockWithKey verifies and uses the already derived key.
+func (s *Store) UnlockWithKey(key []byte) error {
+	if s.IsNew() {
+		return ErrLocked
+	}
+	s.key = key
+	if value, err := s.open(checkName, *s.Check); err != nil || value != checkValue {
+		s.key = nil
+		return ErrBadPassphrase
+	}
+	return nil
+}
+
+// Key returns the derived key of the unlocked store.
+func (s *Store) Key() []byte {
+	return s.key
+}
+
+// Names returns the sorted names of the stored tokens.
+func (s *Store) Names() []string {
+	names := make([]string, 0, len(s.Tokens))
+	for name := range s.Tokens {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get decrypts the token.
+func (s *Store) Get(name string) (string, error) {
+	box, ok := s.Tokens[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return s.open(name, box)
+}
+
+// Set encrypts the token and stores it under the name.
+func (s *Store) Set(name, token string) error {
+	box, err := s.seal(name, token)
+	if err != nil {
+		return err
+	}
+	s.Tokens[name] = box
+	return nil
+}
+
+// Remove deletes the token.
+func (s *Store) Remove(name string) error {
+	if _, ok := s.Tokens[name]; !ok {
+		return ErrNotFound
+	}
+	delete(s.Tokens, name)
+	return nil
+}
+
+// Rekey re-encrypts all tokens with a new salt and a key derived from the new passphrase.
+// The current passphrase must match the store, so a mistyped one cannot replace it.
+func (s *Store) Rekey(current, passphrase string) error {
+	if err := s.UnlockWithKey(deriveKey(current, s.Salt, s.Iterations)); err != nil {
+		return err
+	}
+	tokens := make(map[string]string, len(s.Tokens))
+	for name := range s.Tokens {
+		token, err := s.Get(name)
+		if err != nil {
+			return err
+		}
+		tokens[name] = token
+	}
+	s.Check = nil
+	s.Tokens = make(map[string]sealed, len(tokens))
+	if err := s.Unlock(passphrase); err != nil {
+		return err
+	}
+	for name, token := range tokens {
+		if err := s.Set(name, token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) aead() (cipher.AEAD, error) {
+	if s.key == nil {
+		return nil, ErrLocked
+	}
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the value, the name is authenticated as additional data.
+func (s *Store) seal(name, value string) (sealed, error) {
+	aead, err := s.aead()
+	if err != nil {
+		return sealed{}, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return sealed{}, err
+	}
+	return sealed{nonce, aead.Seal(nil, nonce, []byte(value), []byte(name))}, nil
+}
+
+func (s *Store) open(name string, box sealed) (string, error) {
+	aead, err := s.aead()
+	if err != nil {
+		return "", err
+	}
+	value, err := aead.Open(nil, box.Nonce, box.Data, []byte(name))
+	if err != nil {
+		return "", ErrBadPassphrase
+	}
+	return string(value), nil
+}
+
+// deriveKey implements PBKDF2 (RFC 2898) with HMAC-SHA256.
+func deriveKey(passphrase string, salt []byte, iterations int) []byte {
+	prf := hmac.New(sha256.New, []byte(passphrase))
+	hashLen := prf.Size()
+	blocks := (keyLength + hashLen - 1) / hashLen
+	key := make([]byte, 0, blocks*hashLen)
+	buf := make([]byte, 4)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf, uint32(block))
+		prf.Write(buf)
+		u := prf.Sum(nil)
+		t := make([]byte, len(u))
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLength]
+}
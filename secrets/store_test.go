@@ -0,0 +1,155 @@
+// Copyright © 2015 Alexandr Medvedev <alexandr.mdr@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package secrets
+
+import (
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+)
+
+const testIterations = 1000
+
+func newTestStore(t *testing.T, passphrase string, tokens map[string]string) string {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Iterations = testIterations
+	if err := s.Unlock(passphrase); err != nil {
+		t.Fatal(err)
+	}
+	for name, token := range tokens {
+		if err := s.Set(name, token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func openTestStore(t *testing.T, path string) *Store {
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestDeriveKey(t *testing.T) {
+	// RFC 7914, section 11: PBKDF2-HMAC-SHA256 with P="passwd", S="salt", c=1
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc"
+	if got := hex.EncodeToString(deriveKey("passwd", []byte("salt"), 1)); got != want {
+		t.Errorf("deriveKey = %s, want %s", got, want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	path := newTestStore(t, "secret", map[string]string{"work": "token-1", "home": "token-2"})
+
+	s := openTestStore(t, path)
+	if s.IsNew() {
+		t.Fatal("saved store is new")
+	}
+	if err := s.Unlock("secret"); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"work": "token-1", "home": "token-2"} {
+		if got, err := s.Get(name); err != nil || got != want {
+			t.Errorf("Get(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err := s.Get("missing"); err != ErrNotFound {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+
+	// the key handed out by the agent unlocks the store too
+	agent := openTestStore(t, path)
+	if err := agent.UnlockWithKey(s.Key()); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := agent.Get("work"); got != "token-1" {
+		t.Errorf("Get with the agent key = %q", got)
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	path := newTestStore(t, "secret", map[string]string{"work": "token-1"})
+
+	s := openTestStore(t, path)
+	if err := s.Unlock("Secret"); err != ErrBadPassphrase {
+		t.Fatalf("Unlock with a wrong passphrase error = %v, want ErrBadPassphrase", err)
+	}
+	if _, err := s.Get("work"); err != ErrLocked {
+		t.Errorf("Get on a locked store error = %v, want ErrLocked", err)
+	}
+	if err := s.UnlockWithKey(make([]byte, keyLength)); err != ErrBadPassphrase {
+		t.Errorf("UnlockWithKey with a wrong key error = %v, want ErrBadPassphrase", err)
+	}
+}
+
+func TestRekey(t *testing.T) {
+	path := newTestStore(t, "secret", map[string]string{"work": "token-1"})
+
+	s := openTestStore(t, path)
+	if err := s.Unlock("secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Rekey("typo", "typo"); err != ErrBadPassphrase {
+		t.Fatalf("Rekey with a wrong passphrase error = %v, want ErrBadPassphrase", err)
+	}
+	if got, err := s.Get("work"); err == nil && got == "token-1" {
+		t.Fatal("store stays unlocked after a failed Rekey")
+	}
+
+	s = openTestStore(t, path)
+	salt := string(s.Salt)
+	if err := s.Rekey("secret", "changed"); err != nil {
+		t.Fatal(err)
+	}
+	if string(s.Salt) == salt {
+		t.Error("Rekey kept the salt")
+	}
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openTestStore(t, path)
+	if err := s.Unlock("secret"); err != ErrBadPassphrase {
+		t.Errorf("Unlock with the old passphrase error = %v, want ErrBadPassphrase", err)
+	}
+	if err := s.Unlock("changed"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Get("work"); err != nil || got != "token-1" {
+		t.Errorf("Get after Rekey = %q, %v", got, err)
+	}
+}
+
+func TestRekeyNewStore(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "tokens.json"))
+	if err := s.Rekey("secret", "secret"); err != ErrLocked {
+		t.Errorf("Rekey of a new store error = %v, want ErrLocked", err)
+	}
+}